@@ -2,9 +2,19 @@ package main
 
 import (
 	"Auth/config"
+	"Auth/internal/identity"
+	"Auth/internal/notify"
+	"Auth/internal/passwords"
+	"Auth/internal/policy"
+	"Auth/internal/ratelimit"
 	"Auth/internal/server"
+	"Auth/internal/signing"
 	"Auth/pkg"
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
@@ -13,11 +23,66 @@ func main() {
 	db := pkg.InitializeDatabase(cfg)
 	redis := pkg.InitializeRedis(cfg)
 
-	server := server.StartServer(db, redis, cfg)
+	// access tokens and email verification links are the longest lived
+	// tokens the keys sign
+	retention := max(cfg.JWT.ExpirationMinutes, cfg.Email.ExpirationMinutes)
+	keys, err := signing.LoadKeyRing(cfg.JWT, time.Duration(retention)*time.Minute)
+	if err != nil {
+		log.Fatalf("could not load signing keys: %v", err)
+	}
+
+	policies, err := policy.Load(cfg.Policy)
+	if err != nil {
+		log.Fatalf("could not load access policy: %v", err)
+	}
+	go reloadOnSignal(keys, policies)
+
+	limiter, err := ratelimit.New(cfg.RateLimit, ratelimit.WithFallback(redis, ratelimit.NewMemoryCounter()))
+	if err != nil {
+		log.Fatalf("could not load rate limits: %v", err)
+	}
 
-	err := server.Run(":" + cfg.Server.Port)
+	usernamePolicy, err := identity.NewUsernamePolicy(cfg.Username)
+	if err != nil {
+		log.Fatalf("could not load username policy: %v", err)
+	}
+
+	passwordPolicy, err := passwords.New(cfg.Password)
+	if err != nil {
+		log.Fatalf("could not load password policy: %v", err)
+	}
+
+	notifier, err := notify.NewNotifier(cfg.Notifier)
+	if err != nil {
+		log.Fatalf("could not create notifier: %v", err)
+	}
+
+	server := server.StartServer(db, redis, keys, policies, limiter, usernamePolicy, passwordPolicy, notifier, cfg)
+
+	err = server.Run(":" + cfg.Server.Port)
 
 	if err != nil {
 		panic(err)
 	}
 }
+
+// reloadOnSignal re-reads the signing keys and the access policy on SIGHUP,
+// so keys can be rotated and policies changed without restarting the service.
+func reloadOnSignal(keys *signing.KeyRing, policies *policy.Engine) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		if err := keys.Reload(); err != nil {
+			log.Printf("could not reload signing keys: %v", err)
+		} else {
+			log.Printf("signing keys reloaded, current kid: %s", keys.CurrentKeyID())
+		}
+
+		if err := policies.Reload(); err != nil {
+			log.Printf("could not reload access policy: %v", err)
+		} else {
+			log.Printf("access policy reloaded, %d rules", policies.Rules())
+		}
+	}
+}
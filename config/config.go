@@ -4,16 +4,28 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Server   ServerConfig
-	Env      string
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	JWT         JWTConfig
+	Server      ServerConfig
+	Password    PasswordConfig
+	Username    UsernameConfig
+	Email       EmailVerificationConfig
+	Notifier    NotifierConfig
+	MFA         MFAConfig
+	OIDC        OIDCConfig
+	ForwardAuth ForwardAuthConfig
+	Policy      PolicyConfig
+	Lockout     LockoutConfig
+	RateLimit   RateLimitConfig
+	Session     SessionConfig
+	Env         string
 }
 
 type DatabaseConfig struct {
@@ -32,14 +44,139 @@ type RedisConfig struct {
 }
 
 type JWTConfig struct {
-	Secret            string
-	ExpirationMinutes int
+	Secret                   string
+	ExpirationMinutes        int
+	RefreshExpirationMinutes int
+	SigningMethod            string
+	PrivateKeyFile           string
+	KeyID                    string
+	KeysFile                 string
 }
 
 type ServerConfig struct {
 	Port string
+	// TrustedProxies may set X-Forwarded-For, the client IP of requests
+	// from anywhere else is their remote address.
+	TrustedProxies []string
 }
 
+// PasswordConfig holds the password reset settings and the policy new
+// passwords are checked against. MaxBytes cannot exceed bcrypt's 72 bytes,
+// BreachedList is a directory of breached password hash ranges.
+type PasswordConfig struct {
+	ResetExpirationMinutes int
+	MinLength              int
+	MaxBytes               int
+	MinClasses             int
+	DisallowUsername       bool
+	BreachedList           string
+}
+
+type MFAConfig struct {
+	Issuer string
+}
+
+// OIDCConfig holds the issuer identifier put into ID tokens and discovery,
+// the public base URL of the service without a trailing slash.
+type OIDCConfig struct {
+	Issuer string
+}
+
+// ForwardAuthConfig names the headers /auth answers with, for Traefik's
+// authResponseHeaders to copy to the upstream. An empty name disables the
+// header.
+type ForwardAuthConfig struct {
+	UserHeader     string
+	UserIDHeader   string
+	ClientIDHeader string
+	RolesHeader    string
+	ExpiresHeader  string
+}
+
+// PolicyConfig points at the access policy /auth evaluates forwarded
+// requests against.
+type PolicyConfig struct {
+	File string
+}
+
+// UsernameConfig limits the usernames that can be registered. Pattern is a
+// regular expression the normalized, case folded username has to match.
+type UsernameConfig struct {
+	MinLength int
+	MaxLength int
+	Pattern   string
+}
+
+// LockoutConfig limits failed logins. An account or a client IP reaching
+// its number of attempts within the window is locked for LockMinutes, a
+// limit of 0 disables the check.
+type LockoutConfig struct {
+	AccountAttempts int
+	IPAttempts      int
+	WindowMinutes   int
+	LockMinutes     int
+}
+
+// RateLimitConfig lists the request limits of public routes as
+// route:key=requests/window items, empty disables rate limiting.
+type RateLimitConfig struct {
+	Rules string
+}
+
+// EmailVerificationConfig controls the verification of the email address
+// given at registration. With Required, registration needs an email and
+// unverified accounts cannot log in. URL is the page verification links
+// point to, the token is appended as a query parameter.
+type EmailVerificationConfig struct {
+	Required              bool
+	URL                   string
+	ExpirationMinutes     int
+	ResendCooldownSeconds int
+}
+
+// SessionConfig enables logins with a session cookie instead of tokens.
+// Sessions end after IdleTimeoutMinutes without a request, and after
+// AbsoluteTimeoutMinutes however active they are. SameSite is strict, lax
+// or none.
+type SessionConfig struct {
+	Enabled                bool
+	CookieName             string
+	CookieDomain           string
+	Secure                 bool
+	SameSite               string
+	IdleTimeoutMinutes     int
+	AbsoluteTimeoutMinutes int
+	CSRF                   CSRFConfig
+}
+
+// CSRFConfig protects requests authenticated with the session cookie. Their
+// non-safe requests must echo the token of the CookieName cookie in the
+// HeaderName header. The Origin, or else the Referer, must be the host
+// itself or one of TrustedOrigins; without TrustedOrigins any origin
+// passes. With RequireOrigin requests sending neither are refused.
+type CSRFConfig struct {
+	CookieName     string
+	HeaderName     string
+	TrustedOrigins []string
+	RequireOrigin  bool
+}
+
+type NotifierConfig struct {
+	Driver   string
+	FilePath string
+	SMTP     SMTPConfig
+}
+
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+const defaultRateLimits = "login:ip=30/1m,login:username=10/1m,login_mfa:ip=30/1m,oauth_authorize:ip=30/1m," +
+	"register:ip=5/1h,verify_email_resend:ip=5/1h,password_forgot:ip=5/1h,password_reset:ip=10/1h,oauth_token:client_id=60/1m"
+
 func LoadConfig(envFile string) *Config {
 	if err := godotenv.Load(envFile); err != nil {
 		log.Fatalf("Error loading environment file %s: %v", envFile, err)
@@ -55,6 +192,16 @@ func LoadConfig(envFile string) *Config {
 		log.Fatalf("Invalid JWT_EXPIRATION_MINUTES value: %v", err)
 	}
 
+	jwtRefreshExp, err := strconv.Atoi(os.Getenv("JWT_REFRESH_EXPIRATION_MINUTES"))
+	if err != nil {
+		log.Fatalf("Invalid JWT_REFRESH_EXPIRATION_MINUTES value: %v", err)
+	}
+
+	resetExp, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_EXPIRATION_MINUTES"))
+	if err != nil {
+		log.Fatalf("Invalid PASSWORD_RESET_EXPIRATION_MINUTES value: %v", err)
+	}
+
 	return &Config{
 		Database: DatabaseConfig{
 			Host:     os.Getenv("DB_HOST"),
@@ -70,12 +217,119 @@ func LoadConfig(envFile string) *Config {
 			DB:       redisDB,
 		},
 		JWT: JWTConfig{
-			Secret:            os.Getenv("JWT_SECRET"),
-			ExpirationMinutes: jwtExp,
+			Secret:                   os.Getenv("JWT_SECRET"),
+			ExpirationMinutes:        jwtExp,
+			RefreshExpirationMinutes: jwtRefreshExp,
+			SigningMethod:            os.Getenv("JWT_SIGNING_METHOD"),
+			PrivateKeyFile:           os.Getenv("JWT_PRIVATE_KEY_FILE"),
+			KeyID:                    os.Getenv("JWT_KEY_ID"),
+			KeysFile:                 os.Getenv("JWT_KEYS_FILE"),
 		},
 		Server: ServerConfig{
-			Port: os.Getenv("SERVER_PORT"),
+			Port:           os.Getenv("SERVER_PORT"),
+			TrustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+		},
+		Password: PasswordConfig{
+			ResetExpirationMinutes: resetExp,
+			MinLength:              getEnvInt("PASSWORD_MIN_LENGTH", 10),
+			MaxBytes:               getEnvInt("PASSWORD_MAX_BYTES", 72),
+			MinClasses:             getEnvInt("PASSWORD_MIN_CLASSES", 2),
+			DisallowUsername:       getEnv("PASSWORD_DISALLOW_USERNAME", "true") == "true",
+			BreachedList:           os.Getenv("PASSWORD_BREACHED_LIST"),
+		},
+		Username: UsernameConfig{
+			MinLength: getEnvInt("USERNAME_MIN_LENGTH", 3),
+			MaxLength: getEnvInt("USERNAME_MAX_LENGTH", 32),
+			Pattern:   getEnv("USERNAME_PATTERN", `^[\p{L}\p{N}][\p{L}\p{N}._-]*$`),
+		},
+		Notifier: NotifierConfig{
+			Driver:   os.Getenv("NOTIFIER_DRIVER"),
+			FilePath: os.Getenv("NOTIFIER_FILE"),
+			SMTP: SMTPConfig{
+				Addr:     os.Getenv("SMTP_ADDR"),
+				Username: os.Getenv("SMTP_USERNAME"),
+				Password: os.Getenv("SMTP_PASSWORD"),
+				From:     os.Getenv("SMTP_FROM"),
+			},
+		},
+		Email: EmailVerificationConfig{
+			Required:              getEnv("EMAIL_VERIFICATION_REQUIRED", "false") == "true",
+			URL:                   os.Getenv("EMAIL_VERIFICATION_URL"),
+			ExpirationMinutes:     getEnvInt("EMAIL_VERIFICATION_EXPIRATION_MINUTES", 1440),
+			ResendCooldownSeconds: getEnvInt("EMAIL_VERIFICATION_RESEND_COOLDOWN_SECONDS", 60),
+		},
+		MFA: MFAConfig{
+			Issuer: os.Getenv("MFA_ISSUER"),
+		},
+		OIDC: OIDCConfig{
+			Issuer: strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		},
+		ForwardAuth: ForwardAuthConfig{
+			UserHeader:     getEnv("FORWARD_AUTH_USER_HEADER", "X-Auth-User"),
+			UserIDHeader:   getEnv("FORWARD_AUTH_USER_ID_HEADER", "X-Auth-User-Id"),
+			ClientIDHeader: getEnv("FORWARD_AUTH_CLIENT_ID_HEADER", "X-Auth-Client-Id"),
+			RolesHeader:    getEnv("FORWARD_AUTH_ROLES_HEADER", "X-Auth-Roles"),
+			ExpiresHeader:  getEnv("FORWARD_AUTH_EXPIRES_HEADER", "X-Auth-Token-Expires"),
+		},
+		Policy: PolicyConfig{
+			File: os.Getenv("POLICY_FILE"),
+		},
+		Lockout: LockoutConfig{
+			AccountAttempts: getEnvInt("LOGIN_LOCKOUT_ACCOUNT_ATTEMPTS", 5),
+			IPAttempts:      getEnvInt("LOGIN_LOCKOUT_IP_ATTEMPTS", 20),
+			WindowMinutes:   getEnvInt("LOGIN_LOCKOUT_WINDOW_MINUTES", 15),
+			LockMinutes:     getEnvInt("LOGIN_LOCKOUT_MINUTES", 15),
+		},
+		RateLimit: RateLimitConfig{
+			Rules: getEnv("RATE_LIMITS", defaultRateLimits),
+		},
+		Session: SessionConfig{
+			Enabled:                getEnv("SESSION_ENABLED", "false") == "true",
+			CookieName:             getEnv("SESSION_COOKIE_NAME", "auth_session"),
+			CookieDomain:           os.Getenv("SESSION_COOKIE_DOMAIN"),
+			Secure:                 getEnv("SESSION_COOKIE_SECURE", "true") == "true",
+			SameSite:               getEnv("SESSION_COOKIE_SAMESITE", "lax"),
+			IdleTimeoutMinutes:     getEnvInt("SESSION_IDLE_TIMEOUT_MINUTES", 30),
+			AbsoluteTimeoutMinutes: getEnvInt("SESSION_ABSOLUTE_TIMEOUT_MINUTES", 720),
+			CSRF: CSRFConfig{
+				CookieName:     getEnv("CSRF_COOKIE_NAME", "csrf_token"),
+				HeaderName:     getEnv("CSRF_HEADER_NAME", "X-CSRF-Token"),
+				TrustedOrigins: splitList(os.Getenv("CSRF_TRUSTED_ORIGINS")),
+				RequireOrigin:  getEnv("CSRF_REQUIRE_ORIGIN", "false") == "true",
+			},
 		},
 		Env: os.Getenv("ENV"),
 	}
 }
+
+// getEnv returns the fallback only when the variable is not set at all, so
+// that it can still be explicitly set to an empty value.
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s value: %v", key, err)
+	}
+	return number
+}
+
+// splitList splits a comma separated value, ignoring empty items.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// accessToken is a verified access token. User is nil for tokens of the
+// client credentials grant, ClientID is empty for tokens from /login.
+type accessToken struct {
+	Claims   jwt.MapClaims
+	ID       string
+	User     *model.User
+	ClientID string
+	Scopes   []string
+	// SessionID is the session of tokens from /login.
+	SessionID string
+}
+
+// verifyAccessToken runs every check a token has to pass to be accepted:
+// signature and expiry, not being an mfa token, neither it nor its session
+// being blacklisted and,
+// for user tokens, the account it was issued to still existing at the
+// token's version and being active, for client tokens, the client still
+// existing with the grant and scopes of the token. Rotating a client's
+// secret does not end its tokens, they stay valid until they expire.
+func verifyAccessToken(
+	ctx context.Context,
+	keys *signing.KeyRing,
+	cache Cache,
+	repo UserFindByUsernameRepository,
+	clients ClientFindRepository,
+	tokenString string,
+) (*accessToken, error) {
+	token, err := jwt.Parse(tokenString, keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+
+	if _, exists := claims["purpose"]; exists {
+		return nil, errors.New("token cannot be used for authentication")
+	}
+
+	tokenID := tokenIDFromClaims(claims, tokenString)
+	if cache != nil {
+		if val, err := Cache.Get(cache, blacklistKey(tokenID)); err == nil && val != "" {
+			return nil, errors.New("token is blacklisted")
+		}
+		if sid := stringClaim(claims, "sid"); sid != "" {
+			if val, err := cache.Get(sessionBlacklistKey(sid)); err == nil && val != "" {
+				return nil, errSessionRevoked
+			}
+		}
+	}
+
+	if err := validateTokenClaims(claims); err != nil {
+		return nil, errors.New("token validation failed: " + err.Error())
+	}
+
+	verified := &accessToken{
+		Claims:    claims,
+		ID:        tokenID,
+		ClientID:  stringClaim(claims, "client_id"),
+		Scopes:    strings.Fields(stringClaim(claims, "scope")),
+		SessionID: stringClaim(claims, "sid"),
+	}
+
+	// Tokens of the client credentials grant have a client instead of a
+	// user as subject.
+	if _, isUser := claims["username"]; !isUser && verified.ClientID != "" {
+		if !clientHoldsToken(ctx, clients, verified) {
+			return nil, errors.New("client not found")
+		}
+		return verified, nil
+	}
+
+	username, exists := claims["username"]
+	if !exists {
+		return nil, errors.New("username claim required")
+	}
+
+	usernameStr, ok := username.(string)
+	if !ok {
+		return nil, errors.New("invalid username claim")
+	}
+
+	user, err := findUser(ctx, repo, cache, usernameStr)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if !sameAccount(user, userIDClaim(claims), tokenVersion(claims)) {
+		return nil, errors.New("token has been revoked")
+	}
+	if err := accountStatusError(user); err != nil {
+		return nil, err
+	}
+
+	verified.User = user
+	return verified, nil
+}
+
+// clientHoldsToken reports whether the client of a client credentials token
+// may still get it. Deleting the client, or taking the grant or a scope of
+// the token away from it, ends its tokens.
+func clientHoldsToken(ctx context.Context, clients ClientFindRepository, token *accessToken) bool {
+	if clients == nil {
+		return false
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	client, err := clients.FindByClientID(reqCtx, token.ClientID)
+	if err != nil || !client.AllowsGrant(model.GrantClientCredentials) {
+		return false
+	}
+	_, ok := client.GrantScopes(token.Scopes)
+	return ok
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Only active accounts log in and use their tokens, sessions and API keys.
+// The check runs on the user record findUser caches in Redis, every status
+// change drops the cached copy with forgetUser.
+
+var (
+	errAccountDisabled            = errors.New("account disabled")
+	errAccountLocked              = errors.New("account locked")
+	errAccountPendingVerification = errors.New("email not verified")
+)
+
+// accountStatusError returns why the user cannot sign in, nil for active
+// users.
+func accountStatusError(user *model.User) error {
+	switch user.AccountStatus() {
+	case model.UserStatusActive:
+		return nil
+	case model.UserStatusLocked:
+		return errAccountLocked
+	case model.UserStatusPendingVerification:
+		return errAccountPendingVerification
+	default:
+		return errAccountDisabled
+	}
+}
+
+// respondAccountStatus refuses a login of a user who is not active, with a
+// code per status for clients to tell them apart.
+func respondAccountStatus(ctx *gin.Context, err error) {
+	code := "account_disabled"
+	switch {
+	case errors.Is(err, errAccountLocked):
+		code = "account_locked"
+	case errors.Is(err, errAccountPendingVerification):
+		code = "account_pending_verification"
+	}
+	ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": code})
+}
@@ -0,0 +1,63 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogin_RefusesInactiveAccounts(t *testing.T) {
+	tests := []struct {
+		status model.UserStatus
+		error  string
+		code   string
+	}{
+		{model.UserStatusDisabled, "account disabled", "account_disabled"},
+		{model.UserStatusLocked, "account locked", "account_locked"},
+		{model.UserStatusPendingVerification, "email not verified", "account_pending_verification"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			r, repo, _, _ := newAdminRouter(t)
+			repo.Users[1].SetStatus(tt.status, "")
+
+			resp := postJSON(r, "/login", auth.AuthInput{Username: "alice", Password: "secret"})
+			require.Equal(t, http.StatusForbidden, resp.Code)
+			assert.Contains(t, resp.Body.String(), `"error":"`+tt.error+`"`)
+			assert.Contains(t, resp.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
+
+func TestLogin_InactiveAccountNeedsThePassword(t *testing.T) {
+	r, repo, _, _ := newAdminRouter(t)
+	repo.Users[1].SetStatus(model.UserStatusLocked, "")
+
+	resp := postJSON(r, "/login", auth.AuthInput{Username: "alice", Password: "wrong"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "locked")
+}
+
+func TestAuthHandler_RejectsTokensOfDeactivatedUsers(t *testing.T) {
+	r, repo, store, _ := newAdminRouter(t)
+	alice := login(t, r, "alice", "secret")["token"].(string)
+
+	// the status is checked on the cached user record, without a token
+	// version bump the change shows once the cached copy is gone
+	repo.Users[1].SetStatus(model.UserStatusLocked, "suspicious activity")
+	assert.Equal(t, http.StatusOK, getAuth(r, alice).Code)
+
+	delete(store, "user:alice")
+	resp := getAuth(r, alice)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "account locked")
+
+	repo.Users[1].SetStatus(model.UserStatusActive, "")
+	delete(store, "user:alice")
+	assert.Equal(t, http.StatusOK, getAuth(r, alice).Code)
+}
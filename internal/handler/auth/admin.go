@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"Auth/internal/notify"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	adminDefaultPageSize = 20
+	adminMaxPageSize     = 100
+)
+
+// adminUser is how users are shown to admins, without password hashes and
+// MFA secrets.
+type adminUser struct {
+	ID              uint             `json:"id"`
+	Username        string           `json:"username"`
+	Email           string           `json:"email"`
+	EmailVerified   bool             `json:"email_verified"`
+	Status          model.UserStatus `json:"status"`
+	StatusReason    string           `json:"status_reason"`
+	StatusChangedAt *time.Time       `json:"status_changed_at"`
+	TOTPEnabled     bool             `json:"totp_enabled"`
+	Roles           []string         `json:"roles"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
+
+func newAdminUser(user *model.User) adminUser {
+	return adminUser{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		EmailVerified:   user.EmailVerified,
+		Status:          user.AccountStatus(),
+		StatusReason:    user.StatusReason,
+		StatusChangedAt: user.StatusChangedAt,
+		TOTPEnabled:     user.TOTPEnabled,
+		Roles:           user.RoleNames(),
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+}
+
+// AdminUserListHandler lists users a page at a time. The page and per_page
+// query parameters select the page, username, email, role and status
+// filter the users.
+func AdminUserListHandler(repo UserAdminRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		page, err := queryInt(ctx, "page", 1)
+		if err != nil || page < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid page"})
+			return
+		}
+		perPage, err := queryInt(ctx, "per_page", adminDefaultPageSize)
+		if err != nil || perPage < 1 || perPage > adminMaxPageSize {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "per_page must be between 1 and " + strconv.Itoa(adminMaxPageSize)})
+			return
+		}
+
+		filter := model.UserFilter{
+			Username: ctx.Query("username"),
+			Email:    ctx.Query("email"),
+			Role:     ctx.Query("role"),
+			Status:   model.UserStatus(ctx.Query("status")),
+			Offset:   (page - 1) * perPage,
+			Limit:    perPage,
+		}
+		if filter.Status != "" && !filter.Status.Valid() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid status filter"})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		users, total, err := repo.List(reqCtx, filter)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not list users"})
+			return
+		}
+
+		views := make([]adminUser, 0, len(users))
+		for i := range users {
+			views = append(views, newAdminUser(&users[i]))
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"users": views, "page": page, "per_page": perPage, "total": total})
+	}
+}
+
+// AdminUserGetHandler shows the user of /admin/users/:id or
+// /admin/users/by-username/:username.
+func AdminUserGetHandler(repo UserAdminRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findAdminUser(ctx, repo)
+		if !ok {
+			return
+		}
+
+		ctx.JSON(http.StatusOK, newAdminUser(user))
+	}
+}
+
+// AdminUserStatusHandler sets the account status of the user with a
+// reason. Any status but active also logs the user out everywhere.
+func AdminUserStatusHandler(repo UserAdminRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input AdminStatusInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || !input.Status.Valid() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		user, ok := findAdminUser(ctx, repo)
+		if !ok {
+			return
+		}
+		active := input.Status == model.UserStatusActive
+		if !active && user.ID == ctx.GetUint("user_id") {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot deactivate your own account"})
+			return
+		}
+
+		user.SetStatus(input.Status, input.Reason)
+		if err := repo.Update(user); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not update user"})
+			return
+		}
+
+		if !active {
+			if err := logoutEverywhere(repo, cache, user.Username); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke tokens"})
+				return
+			}
+		} else {
+			_ = forgetUser(cache, user.Username)
+		}
+
+		ctx.JSON(http.StatusOK, newAdminUser(user))
+	}
+}
+
+// AdminUserRolesHandler replaces the roles of the user. Tokens carry the
+// roles they were issued with, so the user is logged out everywhere.
+func AdminUserRolesHandler(repo UserAdminRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input AdminRolesInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Roles == nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		user, ok := findAdminUser(ctx, repo)
+		if !ok {
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		names := slices.Compact(slices.Sorted(slices.Values(input.Roles)))
+		roles, err := repo.FindRoles(reqCtx, names)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not load roles"})
+			return
+		}
+		if len(roles) != len(names) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown role", "unknown": unknownRoles(names, roles)})
+			return
+		}
+
+		if err := repo.SetRoles(user, roles); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not set roles"})
+			return
+		}
+		if err := logoutEverywhere(repo, cache, user.Username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke tokens"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, newAdminUser(user))
+	}
+}
+
+// AdminUserLogoutHandler revokes every token and session of the user.
+func AdminUserLogoutHandler(repo UserAdminRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findAdminUser(ctx, repo)
+		if !ok {
+			return
+		}
+
+		if err := logoutEverywhere(repo, cache, user.Username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke tokens"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "user logged out everywhere"})
+	}
+}
+
+// AdminUserPasswordResetHandler forces the user to choose a new password:
+// every token and session of the user is revoked and a reset token is sent
+// like ForgotPasswordHandler does.
+func AdminUserPasswordResetHandler(
+	repo UserAdminRepository,
+	passwordConfig config.PasswordConfig,
+	cache Cache,
+	notifier notify.Notifier,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findAdminUser(ctx, repo)
+		if !ok {
+			return
+		}
+
+		if err := logoutEverywhere(repo, cache, user.Username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke tokens"})
+			return
+		}
+
+		// the reset token is issued for the bumped token version
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByID(reqCtx, user.ID)
+		if err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+
+		if err := sendPasswordReset(ctx.Request.Context(), cache, notifier, passwordConfig, user); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not send password reset"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "password reset sent"})
+	}
+}
+
+// AdminUserDeleteHandler deletes the user like UnregisterHandler deletes
+// the caller.
+func AdminUserDeleteHandler(repo UserAdminRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, ok := findAdminUser(ctx, repo)
+		if !ok {
+			return
+		}
+		if user.ID == ctx.GetUint("user_id") {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot delete your own account"})
+			return
+		}
+
+		sessions, _ := listSessions(cache, user.Username)
+		if err := repo.Delete(user.Username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not delete user"})
+			return
+		}
+
+		_ = forgetUser(cache, user.Username)
+		for _, session := range sessions {
+			_ = dropSession(cache, session)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "user deleted"})
+	}
+}
+
+// findAdminUser loads the user the route names by :id or :username and
+// answers 404 when there is none.
+func findAdminUser(ctx *gin.Context, repo UserAdminRepository) (*model.User, bool) {
+	reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+	defer cancel()
+
+	var (
+		user *model.User
+		err  error
+	)
+	if username := ctx.Param("username"); username != "" {
+		user, err = repo.FindByUsername(reqCtx, username)
+	} else {
+		id, parseErr := strconv.ParseUint(ctx.Param("id"), 10, 64)
+		if parseErr != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return nil, false
+		}
+		user, err = repo.FindByID(reqCtx, uint(id))
+	}
+
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+func unknownRoles(names []string, roles []model.Role) []string {
+	unknown := []string{}
+	for _, name := range names {
+		if !slices.ContainsFunc(roles, func(role model.Role) bool { return role.Name == name }) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+func queryInt(ctx *gin.Context, name string, fallback int) (int, error) {
+	value := ctx.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
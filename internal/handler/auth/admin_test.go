@@ -0,0 +1,221 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newAdminRouter(t *testing.T) (*gin.Engine, *MockUserAdminRepository, map[string]string, *MockNotifier) {
+	gin.SetMode(gin.TestMode)
+	cache, store := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	editorRole := model.Role{Name: "editor", Permissions: []string{"articles:write"}}
+	adminRole := model.Role{Name: "admin"}
+	repo := &MockUserAdminRepository{
+		Roles: []model.Role{editorRole, adminRole},
+		Users: []*model.User{
+			{Model: gorm.Model{ID: 1}, Username: "root", Password: hashPassword("secret"), Roles: []model.Role{adminRole}},
+			{Model: gorm.Model{ID: 2}, Username: "alice", Email: "alice@example.com", Password: hashPassword("secret"), TOTPSecret: "JBSWY3DPEHPK3PXP"},
+			{Model: gorm.Model{ID: 3}, Username: "bob", Password: hashPassword("secret"), Roles: []model.Role{editorRole}},
+		},
+	}
+
+	notifier := &MockNotifier{}
+
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg)
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.GET("/auth", authenticated)
+	admin := r.Group("/admin", authenticated, auth.RequireToken(), auth.RequireRole("admin"))
+	admin.GET("/users", auth.AdminUserListHandler(repo))
+	admin.GET("/users/:id", auth.AdminUserGetHandler(repo))
+	admin.GET("/users/by-username/:username", auth.AdminUserGetHandler(repo))
+	admin.PUT("/users/:id/status", auth.AdminUserStatusHandler(repo, cache))
+	admin.PUT("/users/:id/roles", auth.AdminUserRolesHandler(repo, cache))
+	admin.POST("/users/:id/logout", auth.AdminUserLogoutHandler(repo, cache))
+	admin.POST("/users/:id/password-reset", auth.AdminUserPasswordResetHandler(repo, passwordCfg, cache, notifier))
+	admin.DELETE("/users/:id", auth.AdminUserDeleteHandler(repo, cache))
+	r.POST("/password/reset", auth.ResetPasswordHandler(repo, passwordCfg, testPasswordPolicy, cache))
+	return r, repo, store, notifier
+}
+
+func adminJSON(r *gin.Engine, method, path, token string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAdminUsers_RequireAdminRole(t *testing.T) {
+	r, _, _, _ := newAdminRouter(t)
+	alice := login(t, r, "alice", "secret")["token"].(string)
+
+	assert.Equal(t, http.StatusForbidden, adminRequest(r, http.MethodGet, "/admin/users", alice).Code)
+	assert.Equal(t, http.StatusForbidden, adminRequest(r, http.MethodDelete, "/admin/users/3", alice).Code)
+}
+
+func TestAdminUserListHandler(t *testing.T) {
+	r, repo, _, _ := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+
+	resp := adminRequest(r, http.MethodGet, "/admin/users", root)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.NotContains(t, resp.Body.String(), "password")
+	assert.NotContains(t, resp.Body.String(), "JBSWY3DPEHPK3PXP")
+
+	var out struct {
+		Users []struct {
+			ID       uint     `json:"id"`
+			Username string   `json:"username"`
+			Roles    []string `json:"roles"`
+		} `json:"users"`
+		Total int `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	assert.Equal(t, 3, out.Total)
+	require.Len(t, out.Users, 3)
+	assert.Equal(t, []string{"editor"}, out.Users[2].Roles)
+
+	resp = adminRequest(r, http.MethodGet, "/admin/users?page=2&per_page=2&username=AL&email=example&role=editor&status=locked", root)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, model.UserFilter{
+		Username: "AL",
+		Email:    "example",
+		Role:     "editor",
+		Status:   model.UserStatusLocked,
+		Offset:   2,
+		Limit:    2,
+	}, repo.LastFilter)
+
+	assert.Equal(t, http.StatusBadRequest, adminRequest(r, http.MethodGet, "/admin/users?per_page=1000", root).Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(r, http.MethodGet, "/admin/users?page=0", root).Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(r, http.MethodGet, "/admin/users?status=banned", root).Code)
+}
+
+func TestAdminUserGetHandler(t *testing.T) {
+	r, _, _, _ := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+
+	resp := adminRequest(r, http.MethodGet, "/admin/users/2", root)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"username":"alice"`)
+
+	resp = adminRequest(r, http.MethodGet, "/admin/users/by-username/Alice", root)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"id":2`)
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(r, http.MethodGet, "/admin/users/99", root).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(r, http.MethodGet, "/admin/users/by-username/nobody", root).Code)
+	assert.Equal(t, http.StatusBadRequest, adminRequest(r, http.MethodGet, "/admin/users/alice", root).Code)
+}
+
+func TestAdminUserStatusHandler(t *testing.T) {
+	r, repo, _, _ := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+	alice := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := adminJSON(r, http.MethodPut, "/admin/users/2/status", root, auth.AdminStatusInput{Status: model.UserStatusDisabled, Reason: "chargeback"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"status":"disabled"`)
+	assert.Contains(t, resp.Body.String(), `"status_reason":"chargeback"`)
+	assert.NotNil(t, repo.Users[1].StatusChangedAt)
+
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, alice).Code)
+	resp = postJSON(r, "/login", auth.AuthInput{Username: "alice", Password: "secret"})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"code":"account_disabled"`)
+
+	resp = adminJSON(r, http.MethodPut, "/admin/users/2/status", root, auth.AdminStatusInput{Status: model.UserStatusActive})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, repo.Users[1].StatusReason)
+	login(t, r, "alice", "secret")
+
+	resp = adminJSON(r, http.MethodPut, "/admin/users/2/status", root, gin.H{"status": "banned"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = adminJSON(r, http.MethodPut, "/admin/users/1/status", root, auth.AdminStatusInput{Status: model.UserStatusLocked})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "cannot deactivate your own account")
+}
+
+func TestAdminUserRolesHandler(t *testing.T) {
+	r, repo, _, _ := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+	alice := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := adminJSON(r, http.MethodPut, "/admin/users/2/roles", root, auth.AdminRolesInput{Roles: []string{"editor", "owner"}})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"unknown":["owner"]`)
+	assert.Empty(t, repo.Users[1].Roles)
+
+	resp = adminJSON(r, http.MethodPut, "/admin/users/2/roles", root, auth.AdminRolesInput{Roles: []string{"editor", "editor"}})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, []string{"editor"}, repo.Users[1].RoleNames())
+
+	// the old token does not carry the new roles
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, alice).Code)
+
+	resp = adminJSON(r, http.MethodPut, "/admin/users/2/roles", root, auth.AdminRolesInput{Roles: []string{}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, repo.Users[1].Roles)
+
+	assert.Equal(t, http.StatusBadRequest, adminJSON(r, http.MethodPut, "/admin/users/2/roles", root, gin.H{}).Code)
+}
+
+func TestAdminUserLogoutHandler(t *testing.T) {
+	r, _, _, _ := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+	bob := login(t, r, "bob", "secret")["token"].(string)
+
+	require.Equal(t, http.StatusOK, adminRequest(r, http.MethodPost, "/admin/users/3/logout", root).Code)
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, bob).Code)
+}
+
+func TestAdminUserPasswordResetHandler(t *testing.T) {
+	r, repo, _, notifier := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+	alice := login(t, r, "alice", "secret")["token"].(string)
+
+	require.Equal(t, http.StatusOK, adminRequest(r, http.MethodPost, "/admin/users/2/password-reset", root).Code)
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, alice).Code)
+	assert.Equal(t, 1, repo.Users[1].TokenVersion)
+
+	sent := notifier.waitForSent(t, 1)
+	assert.Equal(t, "alice@example.com", sent[0].To)
+	match := resetTokenPattern.FindStringSubmatch(sent[0].Body)
+	require.Len(t, match, 2)
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: match[1], NewPassword: "remembered"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, repo.Users[1].CheckPassword("remembered"))
+
+	assert.Equal(t, http.StatusNotFound, adminRequest(r, http.MethodPost, "/admin/users/9/password-reset", root).Code)
+}
+
+func TestAdminUserDeleteHandler(t *testing.T) {
+	r, repo, _, _ := newAdminRouter(t)
+	root := login(t, r, "root", "secret")["token"].(string)
+	bob := login(t, r, "bob", "secret")["token"].(string)
+
+	require.Equal(t, http.StatusOK, adminRequest(r, http.MethodDelete, "/admin/users/3", root).Code)
+	assert.Len(t, repo.Users, 2)
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, bob).Code)
+	assert.Equal(t, http.StatusNotFound, adminRequest(r, http.MethodDelete, "/admin/users/3", root).Code)
+
+	resp := adminRequest(r, http.MethodDelete, "/admin/users/1", root)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Len(t, repo.Users, 2)
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiKeyPrefix = "ak_"
+
+// apiKeyTouchInterval limits how often the last use of a key is written to
+// the database, a busy CI job would otherwise update it on every request.
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyCreateHandler creates an API key for the signed in user. The key is
+// returned once and only its hash is kept. Scopes must be permissions the
+// user holds; a key without scopes acts with all of the user's roles and
+// permissions.
+func APIKeyCreateHandler(repo APIKeyRepository, users UserFindByUsernameRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input APIKeyInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Name == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+
+		if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		user, err := findUser(ctx.Request.Context(), users, cache, ctx.GetString("username"))
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+
+		granted := user.Permissions()
+		for _, scope := range input.Scopes {
+			if !slices.Contains(granted, scope) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "scope not held by the user: " + scope})
+				return
+			}
+		}
+
+		key, prefix, err := newAPIKey()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not create api key"})
+			return
+		}
+
+		apiKey := &model.APIKey{
+			UserID:       user.ID,
+			Name:         input.Name,
+			Prefix:       prefix,
+			Hash:         hashToken(key),
+			Scopes:       input.Scopes,
+			ExpiresAt:    input.ExpiresAt,
+			TokenVersion: user.TokenVersion,
+		}
+		if err := repo.Create(apiKey); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not create api key"})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, gin.H{"key": key, "api_key": apiKey})
+	}
+}
+
+func APIKeyListHandler(repo APIKeyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		keys, err := repo.ListByUser(reqCtx, ctx.GetUint("user_id"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not list api keys"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"api_keys": keys})
+	}
+}
+
+func APIKeyRevokeHandler(repo APIKeyRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid api key id"})
+			return
+		}
+
+		if err := repo.Revoke(ctx.GetUint("user_id"), uint(id)); err != nil {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "api key revoked"})
+	}
+}
+
+// RequireToken keeps API keys away from routes managing the account itself,
+// so that a leaked key cannot be used to mint more keys, change the password
+// or delete the user.
+func RequireToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, isAPIKey := ctx.Get("api_key_id"); isAPIKey {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed with an api key"})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// apiKeyFromRequest returns the key of an "Authorization: ApiKey <key>" or
+// "X-API-Key: <key>" header.
+func apiKeyFromRequest(ctx *gin.Context) string {
+	if key, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "ApiKey "); ok {
+		return key
+	}
+	return ctx.GetHeader("X-API-Key")
+}
+
+// verifyAPIKey returns the key and its user when the key is valid, and
+// records that it was used. Keys are revoked along with the user's tokens,
+// anything bumping the token version ends them as well.
+func verifyAPIKey(
+	ctx context.Context,
+	apiKeys APIKeyFindRepository,
+	users UserFindByUsernameRepository,
+	cache Cache,
+	key string,
+) (*model.APIKey, *model.User, error) {
+	if apiKeys == nil || !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	apiKey, err := apiKeys.FindByHash(reqCtx, hashToken(key))
+	if err != nil || apiKey.User == nil {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	now := time.Now()
+	if apiKey.Expired(now) {
+		return nil, nil, errors.New("api key expired")
+	}
+
+	user, err := findUser(ctx, users, cache, apiKey.User.Username)
+	if err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+	if !sameAccount(user, apiKey.UserID, apiKey.TokenVersion) {
+		return nil, nil, errors.New("api key has been revoked")
+	}
+	if err := accountStatusError(user); err != nil {
+		return nil, nil, err
+	}
+
+	if first, err := cache.SetNX(fmt.Sprintf("api_key_used:%d", apiKey.ID), "used", apiKeyTouchInterval); err == nil && first {
+		_ = apiKeys.TouchLastUsed(apiKey.ID, now)
+	}
+
+	return apiKey, user, nil
+}
+
+// newAPIKey returns a key and the prefix shown to identify it.
+func newAPIKey() (string, string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	prefix := apiKeyPrefix + id[:8]
+	return prefix + "_" + secret, prefix, nil
+}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"context"
+	"time"
+)
+
+type APIKeyFindRepository interface {
+	FindByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	TouchLastUsed(id uint, at time.Time) error
+}
+
+type APIKeyRepository interface {
+	APIKeyFindRepository
+	Create(key *model.APIKey) error
+	ListByUser(ctx context.Context, userID uint) ([]model.APIKey, error)
+	Revoke(userID, id uint) error
+}
@@ -0,0 +1,206 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newAPIKeyRouter(t *testing.T) (*gin.Engine, *MockAPIKeyRepository) {
+	return newAPIKeyRouterFor(t, editor())
+}
+
+func newAPIKeyRouterFor(t *testing.T, user *model.User) (*gin.Engine, *MockAPIKeyRepository) {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	user.Model = gorm.Model{ID: 7}
+	repo := &MockUserRepository{User: user}
+	apiKeys := &MockAPIKeyRepository{User: user}
+	authenticated := auth.AuthHandler(keys, cache, repo, apiKeys, nil, sessionCfg)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.GET("/auth", authenticated, auth.AuthorizeHandler(), auth.ForwardAuthHandler(defaultForwardAuthCfg))
+	account := r.Group("", authenticated, auth.RequireToken())
+	account.POST("/api-keys", auth.APIKeyCreateHandler(apiKeys, repo, cache))
+	account.GET("/api-keys", auth.APIKeyListHandler(apiKeys))
+	account.DELETE("/api-keys/:id", auth.APIKeyRevokeHandler(apiKeys))
+	account.POST("/logout", auth.LogoutHandler(refreshTokenCfg, sessionCfg, cache))
+	account.POST("/logout/all", auth.LogoutAllHandler(repo, cache))
+	admin := account.Group("/admin", auth.RequireRole("admin"))
+	admin.GET("/lockouts/users/:username", auth.LockoutStatusHandler(cache))
+	return r, apiKeys
+}
+
+func createAPIKey(t *testing.T, r *gin.Engine, token string, input auth.APIKeyInput) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+	body, _ := json.Marshal(input)
+	req := httptest.NewRequest(http.MethodPost, "/api-keys", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	var out struct {
+		Key string `json:"key"`
+	}
+	_ = json.Unmarshal(resp.Body.Bytes(), &out)
+	return resp, out.Key
+}
+
+func withAPIKey(r *gin.Engine, method, path, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(header, value)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAPIKey_AuthenticatesLikeAToken(t *testing.T) {
+	r, apiKeys := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Regexp(t, `^ak_[0-9a-f]{8}_`, key)
+	assert.NotContains(t, resp.Body.String(), apiKeys.Keys[0].Hash)
+	assert.Equal(t, key[:11], apiKeys.Keys[0].Prefix)
+
+	resp = withAPIKey(r, http.MethodGet, "/auth?role=editor", "Authorization", "ApiKey "+key)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "alice", resp.Header().Get("X-Auth-User"))
+	assert.Equal(t, "7", resp.Header().Get("X-Auth-User-Id"))
+
+	resp = withAPIKey(r, http.MethodGet, "/auth", "X-API-Key", key)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotNil(t, apiKeys.Keys[0].LastUsedAt)
+}
+
+func TestAPIKey_ScopesLimitPermissions(t *testing.T) {
+	r, _ := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp, _ := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci", Scopes: []string{"users:write"}})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci", Scopes: []string{"articles:read"}})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	resp = withAPIKey(r, http.MethodGet, "/auth?permission=articles:read", "X-API-Key", key)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = withAPIKey(r, http.MethodGet, "/auth?permission=articles:write", "X-API-Key", key)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	resp = withAPIKey(r, http.MethodGet, "/auth?role=editor", "X-API-Key", key)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestAPIKey_ExpiredKeyIsRejected(t *testing.T) {
+	r, apiKeys := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	past := time.Now().Add(-time.Hour)
+	resp, _ := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci", ExpiresAt: &past})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	future := time.Now().Add(time.Hour)
+	resp, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci", ExpiresAt: &future})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	apiKeys.Keys[0].ExpiresAt = &past
+	resp = withAPIKey(r, http.MethodGet, "/auth", "X-API-Key", key)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "api key expired")
+}
+
+func TestAPIKey_ListAndRevoke(t *testing.T) {
+	r, _ := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+	_, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+
+	resp := withAPIKey(r, http.MethodGet, "/api-keys", "Authorization", "Bearer "+token)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var list struct {
+		APIKeys []model.APIKey `json:"api_keys"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &list))
+	require.Len(t, list.APIKeys, 1)
+	assert.Equal(t, "ci", list.APIKeys[0].Name)
+	assert.NotContains(t, resp.Body.String(), key)
+
+	resp = withAPIKey(r, http.MethodDelete, "/api-keys/1", "Authorization", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	resp = withAPIKey(r, http.MethodDelete, "/api-keys/1", "Authorization", "Bearer "+token)
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = withAPIKey(r, http.MethodGet, "/auth", "X-API-Key", key)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid api key")
+}
+
+func TestAPIKey_CannotManageTheAccount(t *testing.T) {
+	r, _ := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+	_, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+
+	resp := withAPIKey(r, http.MethodGet, "/api-keys", "X-API-Key", key)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "not allowed with an api key")
+}
+
+func TestAPIKey_CannotLogOut(t *testing.T) {
+	r, _ := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+	_, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+
+	resp := withAPIKey(r, http.MethodPost, "/logout", "X-API-Key", key)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "not allowed with an api key")
+}
+
+func TestAPIKey_EndsWhenTheUserLogsOutEverywhere(t *testing.T) {
+	r, _ := newAPIKeyRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+	_, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+	require.Equal(t, http.StatusOK, withAPIKey(r, http.MethodGet, "/auth", "X-API-Key", key).Code)
+
+	require.Equal(t, http.StatusOK, withAPIKey(r, http.MethodPost, "/logout/all", "Authorization", "Bearer "+token).Code)
+
+	resp := withAPIKey(r, http.MethodGet, "/auth", "X-API-Key", key)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "api key has been revoked")
+
+	token = login(t, r, "alice", "secret")["token"].(string)
+	_, key = createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+	assert.Equal(t, http.StatusOK, withAPIKey(r, http.MethodGet, "/auth", "X-API-Key", key).Code)
+}
+
+func TestAPIKey_CannotUseAdminRoutes(t *testing.T) {
+	r, _ := newAPIKeyRouterFor(t, policyAdmin())
+	token := login(t, r, "alice", "secret")["token"].(string)
+	_, key := createAPIKey(t, r, token, auth.APIKeyInput{Name: "ci"})
+
+	resp := withAPIKey(r, http.MethodGet, "/admin/lockouts/users/bob", "X-API-Key", key)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "not allowed with an api key")
+
+	resp = withAPIKey(r, http.MethodGet, "/admin/lockouts/users/bob", "Authorization", "Bearer "+token)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestAPIKey_UnknownKeyIsRejected(t *testing.T) {
+	r, _ := newAPIKeyRouter(t)
+
+	resp := withAPIKey(r, http.MethodGet, "/auth", "Authorization", "ApiKey ak_00000000_nope")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
@@ -1,6 +1,128 @@
 package auth
 
+import (
+	"Auth/internal/model"
+	"encoding/json"
+	"time"
+)
+
 type AuthInput struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Session asks for a session cookie instead of a token pair.
+	Session bool `json:"session"`
+}
+
+type RegisterInput struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token"`
+}
+
+type AdminRolesInput struct {
+	Roles []string `json:"roles"`
+}
+
+type AdminStatusInput struct {
+	Status model.UserStatus `json:"status" binding:"required"`
+	Reason string           `json:"reason" binding:"max=255"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ForgotPasswordInput struct {
+	Username string `json:"username"`
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type TOTPCodeInput struct {
+	Code string `json:"code"`
+}
+
+type LoginMFAInput struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+	Session      bool   `json:"session"`
+}
+
+// PolicyEvaluateInput is a sample request checked against the current policy
+// or, when given, against a candidate policy. Roles and permissions describe
+// the caller to check the decision for.
+type PolicyEvaluateInput struct {
+	Method      string          `json:"method"`
+	Host        string          `json:"host"`
+	Path        string          `json:"path"`
+	Roles       []string        `json:"roles"`
+	Permissions []string        `json:"permissions"`
+	Policy      json.RawMessage `json:"policy"`
+}
+
+type OAuthClientInput struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+}
+
+// OAuthAuthorizeInput holds the parameters of an authorization request, sent
+// in the query to show the login page and as form fields when it is submitted.
+type OAuthAuthorizeInput struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Scope               string `form:"scope"`
+	Nonce               string `form:"nonce"`
+}
+
+type OAuthConsentInput struct {
+	OAuthAuthorizeInput
+	Username string `form:"username"`
+	Password string `form:"password"`
+	TOTPCode string `form:"totp_code"`
+	Decision string `form:"decision"`
+}
+
+type OAuthTokenInput struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+// OAuthTokenHintInput is the request of both the introspection and the
+// revocation endpoint, RFC 7662 and RFC 7009.
+type OAuthTokenHintInput struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+type APIKeyInput struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
 }
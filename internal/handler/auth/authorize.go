@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"Auth/internal/policy"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthorizeHandler enforces the requirements passed as query parameters of
+// the forward-auth address, e.g. /auth?role=admin&permission=users:write.
+// Traefik calls the address with its query intact, so each protected router
+// can point at its own forwardauth middleware. With several role parameters
+// any of them is enough, every permission parameter is required.
+//
+// Requirements of the policy rule PolicyHandler matched apply on top.
+//
+// It must run after AuthHandler; callers that are authenticated but lack a
+// role or permission get 403 instead of 401.
+func AuthorizeHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !authorize(ctx, ctx.QueryArray("role"), ctx.QueryArray("permission")) {
+			return
+		}
+		if value, ok := ctx.Get(policyDecisionKey); ok {
+			decision := value.(policy.Decision)
+			if !authorize(ctx, decision.Roles, decision.Permissions) {
+				return
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// RequireRole guards routes of this service, any of the roles is enough.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !authorize(ctx, roles, nil) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func authorize(ctx *gin.Context, roles, permissions []string) bool {
+	if ctx.GetString("username") == "" && ctx.GetString("client_id") == "" {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return false
+	}
+
+	if len(roles) > 0 && !hasAny(ctx.GetStringSlice("roles"), roles) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient role"})
+		return false
+	}
+
+	granted := ctx.GetStringSlice("permissions")
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission: " + permission})
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasAny(granted, required []string) bool {
+	for _, value := range required {
+		if slices.Contains(granted, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuthorizeRouter(t *testing.T, user *model.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: user}
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.GET("/auth", authenticated, auth.AuthorizeHandler())
+	r.GET("/admin", authenticated, auth.RequireRole("admin"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "admin area"})
+	})
+	return r
+}
+
+func getWithToken(r *gin.Engine, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func editor() *model.User {
+	return &model.User{
+		Username: "alice",
+		Password: hashPassword("secret"),
+		Roles: []model.Role{
+			{Name: "editor", Permissions: []string{"articles:write", "articles:read"}},
+			{Name: "viewer", Permissions: []string{"articles:read"}},
+		},
+	}
+}
+
+func TestLoginHandler_EmitsRoleClaims(t *testing.T) {
+	r := newAuthorizeRouter(t, editor())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	assert.Equal(t, []any{"editor", "viewer"}, claims["roles"])
+	assert.Equal(t, []any{"articles:read", "articles:write"}, claims["permissions"])
+}
+
+func TestAuthorizeHandler(t *testing.T) {
+	r := newAuthorizeRouter(t, editor())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	tests := []struct {
+		name         string
+		path         string
+		token        string
+		expectedCode int
+		expectedBody string
+	}{
+		{"no requirements", "/auth", token, http.StatusOK, ""},
+		{"matching role", "/auth?role=editor", token, http.StatusOK, ""},
+		{"any of the roles", "/auth?role=admin&role=viewer", token, http.StatusOK, ""},
+		{"missing role", "/auth?role=admin", token, http.StatusForbidden, "insufficient role"},
+		{"granted permission", "/auth?permission=articles:write", token, http.StatusOK, ""},
+		{"all permissions required", "/auth?permission=articles:read&permission=articles:delete", token, http.StatusForbidden, "missing permission: articles:delete"},
+		{"unauthenticated", "/auth?role=editor", "", http.StatusUnauthorized, "authorization header required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := getWithToken(r, tt.path, tt.token)
+			assert.Equal(t, tt.expectedCode, resp.Code)
+			assert.Contains(t, resp.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	r := newAuthorizeRouter(t, editor())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := getWithToken(r, "/admin", token)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	admin := editor()
+	admin.Roles = append(admin.Roles, model.Role{Name: "admin"})
+	r = newAuthorizeRouter(t, admin)
+	token = login(t, r, "alice", "secret")["token"].(string)
+
+	resp = getWithToken(r, "/admin", token)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
@@ -4,6 +4,16 @@ import "time"
 
 type Cache interface {
 	Set(key string, value any, expiration time.Duration) error
+	SetNX(key string, value any, expiration time.Duration) (bool, error)
 	Get(key string) (string, error)
 	Delete(key string) error
+	// Increment adds one to the counter at key and returns the new value. The
+	// expiration starts when the counter is created and is not extended by
+	// later increments, so the counter covers a fixed window.
+	Increment(key string, expiration time.Duration) (int64, error)
+	// AddToSet adds member to the set at key. The expiration of the set is
+	// only ever extended, never shortened, by adding to it.
+	AddToSet(key, member string, expiration time.Duration) error
+	SetMembers(key string) ([]string, error)
+	RemoveFromSet(key, member string) error
 }
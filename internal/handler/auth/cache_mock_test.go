@@ -2,13 +2,21 @@ package auth_test
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"time"
 )
 
 type MockCacheRepository struct {
-	GetFunc    func(key string) (string, error)
-	SetFunc    func(key string, value any, expiration time.Duration) error
-	DeleteFunc func(key string) error
+	GetFunc      func(key string) (string, error)
+	SetFunc      func(key string, value any, expiration time.Duration) error
+	SetNXFunc    func(key string, value any, expiration time.Duration) (bool, error)
+	DeleteFunc   func(key string) error
+	IncrFunc     func(key string, expiration time.Duration) (int64, error)
+	SAddFunc     func(key, member string, expiration time.Duration) error
+	SMembersFunc func(key string) ([]string, error)
+	SRemFunc     func(key, member string) error
 }
 
 func (m *MockCacheRepository) Get(key string) (string, error) {
@@ -31,3 +39,95 @@ func (m *MockCacheRepository) Set(key string, value any, expiration time.Duratio
 	}
 	return nil
 }
+
+func (m *MockCacheRepository) SetNX(key string, value any, expiration time.Duration) (bool, error) {
+	if m.SetNXFunc != nil {
+		return m.SetNXFunc(key, value, expiration)
+	}
+	return true, nil
+}
+
+func (m *MockCacheRepository) Increment(key string, expiration time.Duration) (int64, error) {
+	if m.IncrFunc != nil {
+		return m.IncrFunc(key, expiration)
+	}
+	return 1, nil
+}
+
+func (m *MockCacheRepository) AddToSet(key, member string, expiration time.Duration) error {
+	if m.SAddFunc != nil {
+		return m.SAddFunc(key, member, expiration)
+	}
+	return nil
+}
+
+func (m *MockCacheRepository) SetMembers(key string) ([]string, error) {
+	if m.SMembersFunc != nil {
+		return m.SMembersFunc(key)
+	}
+	return nil, nil
+}
+
+func (m *MockCacheRepository) RemoveFromSet(key, member string) error {
+	if m.SRemFunc != nil {
+		return m.SRemFunc(key, member)
+	}
+	return nil
+}
+
+// newInMemoryCache returns a mock backed by a plain map, for tests that need
+// the cache to remember what handlers wrote into it. Expiration is ignored,
+// sets are kept apart from the returned store.
+func newInMemoryCache() (*MockCacheRepository, map[string]string) {
+	store := map[string]string{}
+	sets := map[string][]string{}
+	return &MockCacheRepository{
+		GetFunc: func(key string) (string, error) {
+			if val, ok := store[key]; ok {
+				return val, nil
+			}
+			return "", errors.New("not found")
+		},
+		SetFunc: func(key string, value any, expiration time.Duration) error {
+			store[key] = stringify(value)
+			return nil
+		},
+		SetNXFunc: func(key string, value any, expiration time.Duration) (bool, error) {
+			if _, ok := store[key]; ok {
+				return false, nil
+			}
+			store[key] = stringify(value)
+			return true, nil
+		},
+		DeleteFunc: func(key string) error {
+			delete(store, key)
+			return nil
+		},
+		IncrFunc: func(key string, expiration time.Duration) (int64, error) {
+			count, _ := strconv.ParseInt(store[key], 10, 64)
+			count++
+			store[key] = strconv.FormatInt(count, 10)
+			return count, nil
+		},
+		SAddFunc: func(key, member string, expiration time.Duration) error {
+			if !slices.Contains(sets[key], member) {
+				sets[key] = append(sets[key], member)
+			}
+			return nil
+		},
+		SMembersFunc: func(key string) ([]string, error) {
+			return slices.Clone(sets[key]), nil
+		},
+		SRemFunc: func(key, member string) error {
+			sets[key] = slices.DeleteFunc(sets[key], func(m string) bool { return m == member })
+			return nil
+		},
+	}, store
+}
+
+func stringify(value any) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
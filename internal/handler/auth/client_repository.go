@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"context"
+)
+
+type ClientCreateRepository interface {
+	Create(client *model.Client) error
+}
+
+type ClientFindRepository interface {
+	FindByClientID(ctx context.Context, clientID string) (*model.Client, error)
+}
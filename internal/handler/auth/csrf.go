@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"Auth/config"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Browsers send the session cookie with requests other sites trigger, so
+// requests authenticated with it have to prove they come from our own pages.
+// Every cookie session has a synchronizer token, handed to the browser in a
+// cookie scripts can read, that non-safe requests must echo in a header.
+// Requests with a Bearer token or an API key need no such proof, other
+// sites cannot make the browser send those.
+
+var (
+	errCSRFToken  = errors.New("missing or invalid csrf token")
+	errCSRFOrigin = errors.New("request origin not allowed")
+)
+
+func isCSRFError(err error) bool {
+	return errors.Is(err, errCSRFToken) || errors.Is(err, errCSRFOrigin)
+}
+
+// checkCSRF lets safe requests pass and checks the origin and the token of
+// all others.
+func checkCSRF(ctx *gin.Context, csrfConfig config.CSRFConfig, session *sessionRecord) error {
+	if isSafeMethod(csrfMethod(ctx)) {
+		return nil
+	}
+
+	if !originAllowed(ctx, csrfConfig) {
+		return errCSRFOrigin
+	}
+
+	token := ctx.GetHeader(csrfConfig.HeaderName)
+	if session.CSRFToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) != 1 {
+		return errCSRFToken
+	}
+	return nil
+}
+
+// csrfMethod is the method of the request to protect. Traefik asks /auth
+// with GET and the original method in X-Forwarded-Method, which is only
+// trusted to make a safe request stricter.
+func csrfMethod(ctx *gin.Context) string {
+	if isSafeMethod(ctx.Request.Method) {
+		return forwardedRequest(ctx).Method
+	}
+	return ctx.Request.Method
+}
+
+func isSafeMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// originAllowed checks the Origin header, or the origin of the Referer when
+// the browser sent none.
+func originAllowed(ctx *gin.Context, csrfConfig config.CSRFConfig) bool {
+	origin := ctx.GetHeader("Origin")
+	if origin == "" || origin == "null" {
+		if referer, err := url.Parse(ctx.GetHeader("Referer")); err == nil && referer.Host != "" {
+			origin = referer.Scheme + "://" + referer.Host
+		}
+	}
+
+	if origin == "" || origin == "null" {
+		return !csrfConfig.RequireOrigin
+	}
+	if len(csrfConfig.TrustedOrigins) == 0 {
+		return true
+	}
+
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, forwardedRequest(ctx).Host) {
+		return true
+	}
+	return slices.ContainsFunc(csrfConfig.TrustedOrigins, func(trusted string) bool {
+		return strings.EqualFold(strings.TrimSuffix(trusted, "/"), origin)
+	})
+}
+
+func setCSRFCookie(ctx *gin.Context, sessionConfig config.SessionConfig, token string, maxAge time.Duration) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     sessionConfig.CSRF.CookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   sessionConfig.CookieDomain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   sessionConfig.Secure,
+		SameSite: sameSite(sessionConfig.SameSite),
+	})
+}
@@ -0,0 +1,101 @@
+package auth_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF_CookieIsReadableByScripts(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+
+	resp := postJSON(r, "/login", map[string]any{"username": "alice", "password": "secret", "session": true})
+	require.Equal(t, http.StatusOK, resp.Code)
+	for _, cookie := range resp.Result().Cookies() {
+		if cookie.Name == sessionCfg.CSRF.CookieName {
+			assert.False(t, cookie.HttpOnly)
+			assert.True(t, cookie.Secure)
+			assert.Contains(t, resp.Body.String(), cookie.Value)
+			return
+		}
+	}
+	t.Fatal("no csrf cookie set")
+}
+
+func TestCSRF_NonSafeRequestsNeedToken(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, csrfToken := sessionLogin(t, r)
+
+	resp := withCookie(r, http.MethodPost, "/logout", cookie)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "missing or invalid csrf token")
+
+	resp = withCSRF(r, http.MethodPost, "/logout", cookie, "forged")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	// refused requests leave the session alone
+	assert.Equal(t, http.StatusOK, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+	assert.Equal(t, http.StatusOK, withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken).Code)
+}
+
+func TestCSRF_Origin(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, csrfToken := sessionLogin(t, r)
+
+	resp := withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken, "Origin", "https://evil.example")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "request origin not allowed")
+
+	resp = withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken, "Referer", "https://evil.example/page")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken, "Origin", "https://app.example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestCSRF_SameHostOrigin(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, csrfToken := sessionLogin(t, r)
+
+	// httptest requests go to example.com
+	resp := withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken, "Referer", "http://example.com/account")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestCSRF_RequireOrigin(t *testing.T) {
+	strict := sessionCfg
+	strict.CSRF.RequireOrigin = true
+	r, _, _ := newSessionRouter(t, strict)
+	cookie, csrfToken := sessionLogin(t, r)
+
+	resp := withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "request origin not allowed")
+
+	resp = withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken, "Origin", "https://app.example.com")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestCSRF_ForwardedRequests(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, csrfToken := sessionLogin(t, r)
+
+	resp := withCSRF(r, http.MethodGet, "/auth", cookie, "", "X-Forwarded-Method", "DELETE")
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = withCSRF(r, http.MethodGet, "/auth", cookie, csrfToken, "X-Forwarded-Method", "DELETE")
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = withCSRF(r, http.MethodGet, "/auth", cookie, "", "X-Forwarded-Method", "GET")
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestCSRF_BearerTokensAreExempt(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	pair := login(t, r, "alice", "secret")
+
+	resp := postLogout(r, pair["token"].(string), map[string]any{})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/identity"
+	"Auth/internal/model"
+	"Auth/internal/notify"
+	"Auth/internal/signing"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const emailVerificationPurpose = "email_verification"
+
+var errVerificationTokenInvalid = errors.New("invalid or expired verification token")
+
+// VerifyEmailHandler consumes a verification token, from the query of the
+// emailed link (GET) or a JSON body (POST). Every token works once and only
+// for the email address it was issued for.
+func VerifyEmailHandler(repo UserPasswordRepository, keys *signing.KeyRing, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := ctx.Query("token")
+		if ctx.Request.Method == http.MethodPost {
+			var input VerifyEmailInput
+			if err := ctx.ShouldBindJSON(&input); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+				return
+			}
+			token = input.Token
+		}
+
+		claims, err := parseEmailVerificationToken(keys, token)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errVerificationTokenInvalid.Error()})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, stringClaim(claims, "sub"))
+		if err != nil || user.Email == "" || user.Email != stringClaim(claims, "email") {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errVerificationTokenInvalid.Error()})
+			return
+		}
+
+		expiresAt, _ := claims.GetExpirationTime()
+		if first, err := cache.SetNX("email_verification_used:"+stringClaim(claims, "jti"), "used", time.Until(expiresAt.Time)); err != nil || !first {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errVerificationTokenInvalid.Error()})
+			return
+		}
+
+		if !user.EmailVerified {
+			now := time.Now()
+			user.EmailVerified = true
+			user.EmailVerifiedAt = &now
+			if user.AccountStatus() == model.UserStatusPendingVerification {
+				user.SetStatus(model.UserStatusActive, "")
+			}
+			if err := repo.Update(user); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify email"})
+				return
+			}
+			_ = forgetUser(cache, user.Username)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "email verified"})
+	}
+}
+
+// ResendVerificationHandler sends a new verification email. Like
+// ForgotPasswordHandler it answers the same way whatever the account, and
+// it sends at most one email per account within the cooldown.
+func ResendVerificationHandler(
+	repo UserFindByUsernameRepository,
+	emailConfig config.EmailVerificationConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+	notifier notify.Notifier,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input ForgotPasswordInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Username == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, input.Username)
+		if err == nil && user.Email != "" && !user.EmailVerified {
+			cooldown := time.Duration(emailConfig.ResendCooldownSeconds) * time.Second
+			key := "email_verification_sent:" + identity.NormalizeUsername(user.Username)
+			if first, err := cache.SetNX(key, "sent", cooldown); err == nil && first {
+				if err := sendEmailVerification(ctx.Request.Context(), emailConfig, keys, notifier, user); err != nil {
+					log.Printf("could not send email verification for %s: %v", user.Username, err)
+				}
+			}
+		}
+
+		ctx.JSON(http.StatusAccepted, gin.H{"message": "if the account has an unverified email, a verification email has been sent"})
+	}
+}
+
+// recipient is where notifications for the user go, users registered
+// without an email address are notified by username.
+func recipient(user *model.User) string {
+	if user.Email != "" {
+		return user.Email
+	}
+	return user.Username
+}
+
+func sendEmailVerification(
+	ctx context.Context,
+	emailConfig config.EmailVerificationConfig,
+	keys *signing.KeyRing,
+	notifier notify.Notifier,
+	user *model.User,
+) error {
+	ttl := time.Duration(emailConfig.ExpirationMinutes) * time.Minute
+	token, err := generateEmailVerificationToken(keys, user, ttl)
+	if err != nil {
+		return err
+	}
+
+	link := token
+	if emailConfig.URL != "" {
+		link = emailConfig.URL + "?" + url.Values{"token": {token}}.Encode()
+	}
+
+	return notifier.Notify(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Confirm the email address of your account %s: %s\nThe link expires in %d minutes.",
+			user.Username, link, emailConfig.ExpirationMinutes,
+		),
+	})
+}
+
+// generateEmailVerificationToken signs the username and email address to be
+// verified. It carries a purpose claim, so it is never accepted as an
+// access token.
+func generateEmailVerificationToken(keys *signing.KeyRing, user *model.User, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return keys.Sign(jwt.MapClaims{
+		"sub":     user.Username,
+		"email":   user.Email,
+		"purpose": emailVerificationPurpose,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	})
+}
+
+func parseEmailVerificationToken(keys *signing.KeyRing, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, keys.Keyfunc, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != emailVerificationPurpose || stringClaim(claims, "jti") == "" {
+		return nil, errVerificationTokenInvalid
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,173 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emailCfg leaves email verification optional, as most tests register no email.
+var emailCfg = config.EmailVerificationConfig{
+	URL:                   "http://auth.local/verify-email",
+	ExpirationMinutes:     60,
+	ResendCooldownSeconds: 60,
+}
+
+var verificationLinkPattern = regexp.MustCompile(`(http://auth.local/verify-email\?\S+)`)
+
+func newEmailVerificationRouter(t *testing.T, emailConfig config.EmailVerificationConfig) (*gin.Engine, *MockUserRepository, *MockNotifier) {
+	return newEmailVerificationRouterWithKeys(t, emailConfig, newTestKeyRing(t, testSecret))
+}
+
+func newEmailVerificationRouterWithKeys(t *testing.T, emailConfig config.EmailVerificationConfig, keys *signing.KeyRing) (*gin.Engine, *MockUserRepository, *MockNotifier) {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	notifier := &MockNotifier{}
+	repo := &MockUserRepository{}
+	repo.CreateFunc = func(user *model.User) error {
+		repo.User = user
+		return nil
+	}
+
+	r := gin.New()
+	r.POST("/register", auth.RegisterHandler(repo, refreshTokenCfg, emailConfig, nil, testPasswordPolicy, keys, cache, notifier))
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailConfig, sessionCfg, keys, cache))
+	r.GET("/verify-email", auth.VerifyEmailHandler(repo, keys, cache))
+	r.POST("/verify-email", auth.VerifyEmailHandler(repo, keys, cache))
+	r.POST("/verify-email/resend", auth.ResendVerificationHandler(repo, emailConfig, keys, cache, notifier))
+	r.GET("/auth", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg))
+	return r, repo, notifier
+}
+
+func verificationLink(t *testing.T, notifier *MockNotifier) string {
+	t.Helper()
+	require.NotEmpty(t, notifier.Sent)
+	msg := notifier.Sent[len(notifier.Sent)-1]
+	match := verificationLinkPattern.FindStringSubmatch(msg.Body)
+	require.Len(t, match, 2, msg.Body)
+	return match[1]
+}
+
+func get(r *gin.Engine, target string) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, target, nil))
+	return resp
+}
+
+func TestEmailVerification_Flow(t *testing.T) {
+	required := emailCfg
+	required.Required = true
+	r, repo, notifier := newEmailVerificationRouter(t, required)
+
+	resp := postJSON(r, "/register", auth.RegisterInput{Username: "alice", Password: "long enough", Email: "Alice@Example.com"})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	require.Len(t, notifier.Sent, 1)
+	assert.Equal(t, "alice@example.com", notifier.Sent[0].To)
+	link := verificationLink(t, notifier)
+	assert.Equal(t, model.UserStatusPendingVerification, repo.User.Status)
+
+	resp = postJSON(r, "/login", auth.AuthInput{Username: "alice", Password: "long enough"})
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "email not verified")
+
+	resp = get(r, link)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.True(t, repo.User.EmailVerified)
+	assert.NotNil(t, repo.User.EmailVerifiedAt)
+	assert.Equal(t, model.UserStatusActive, repo.User.Status)
+
+	resp = get(r, link)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid or expired verification token")
+
+	login(t, r, "alice", "long enough")
+}
+
+func TestEmailVerification_RequiredNeedsEmail(t *testing.T) {
+	required := emailCfg
+	required.Required = true
+	r, _, _ := newEmailVerificationRouter(t, required)
+
+	resp := postJSON(r, "/register", auth.RegisterInput{Username: "alice", Password: "long enough"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "email is required")
+}
+
+func TestEmailVerification_TokenIsBoundToTheEmail(t *testing.T) {
+	r, repo, notifier := newEmailVerificationRouter(t, emailCfg)
+
+	postJSON(r, "/register", auth.RegisterInput{Username: "alice", Password: "long enough", Email: "alice@example.com"})
+	link, err := url.Parse(verificationLink(t, notifier))
+	require.NoError(t, err)
+	token := link.Query().Get("token")
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	repo.User.Email = "mallory@example.com"
+	resp = postJSON(r, "/verify-email", auth.VerifyEmailInput{Token: token})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.False(t, repo.User.EmailVerified)
+}
+
+func TestResendVerificationHandler_Cooldown(t *testing.T) {
+	r, repo, notifier := newEmailVerificationRouter(t, emailCfg)
+	postJSON(r, "/register", auth.RegisterInput{Username: "alice", Password: "long enough", Email: "alice@example.com"})
+	require.Len(t, notifier.Sent, 1)
+
+	for i := 0; i < 2; i++ {
+		resp := postJSON(r, "/verify-email/resend", auth.ForgotPasswordInput{Username: "alice"})
+		assert.Equal(t, http.StatusAccepted, resp.Code)
+	}
+	assert.Len(t, notifier.Sent, 2)
+
+	resp := postJSON(r, "/verify-email", auth.VerifyEmailInput{Token: mustToken(t, verificationLink(t, notifier))})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, repo.User.EmailVerified)
+
+	resp = postJSON(r, "/verify-email/resend", auth.ForgotPasswordInput{Username: "nobody"})
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Len(t, notifier.Sent, 2)
+}
+
+func mustToken(t *testing.T, link string) string {
+	t.Helper()
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestEmailVerification_LinkSurvivesKeyRotation(t *testing.T) {
+	oldKey, err := signing.NewHMACKey("old", testSecret)
+	require.NoError(t, err)
+	newKey, err := signing.NewHMACKey("new", "another secret")
+	require.NoError(t, err)
+
+	// retired keys are kept as long as verification links live, like main
+	// sets up the ring
+	keys := signing.NewKeyRing(time.Duration(emailCfg.ExpirationMinutes)*time.Minute, oldKey)
+	r, repo, notifier := newEmailVerificationRouterWithKeys(t, emailCfg, keys)
+
+	postJSON(r, "/register", auth.RegisterInput{Username: "alice", Password: "long enough", Email: "alice@example.com"})
+	link := verificationLink(t, notifier)
+
+	keys.Replace(newKey)
+
+	resp := get(r, link)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.True(t, repo.User.EmailVerified)
+}
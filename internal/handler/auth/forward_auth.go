@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"Auth/config"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForwardAuthHandler answers Traefik's forward-auth request once AuthHandler
+// accepted it, passing the caller's identity on in response headers. Callers
+// are users or, with client credentials tokens, clients.
+func ForwardAuthHandler(forwardAuthConfig config.ForwardAuthConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		setHeader(ctx, forwardAuthConfig.UserHeader, ctx.GetString("username"))
+
+		if userID := ctx.GetUint("user_id"); userID != 0 {
+			setHeader(ctx, forwardAuthConfig.UserIDHeader, strconv.FormatUint(uint64(userID), 10))
+		}
+
+		setHeader(ctx, forwardAuthConfig.ClientIDHeader, ctx.GetString("client_id"))
+		setHeader(ctx, forwardAuthConfig.RolesHeader, strings.Join(ctx.GetStringSlice("roles"), ","))
+
+		if expiresAt := ctx.GetTime("token_expires_at"); !expiresAt.IsZero() {
+			setHeader(ctx, forwardAuthConfig.ExpiresHeader, strconv.FormatInt(expiresAt.Unix(), 10))
+		}
+
+		ctx.Status(http.StatusOK)
+	}
+}
+
+func setHeader(ctx *gin.Context, name, value string) {
+	if name != "" && value != "" {
+		ctx.Header(name, value)
+	}
+}
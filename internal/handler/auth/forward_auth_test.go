@@ -0,0 +1,76 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var defaultForwardAuthCfg = config.ForwardAuthConfig{
+	UserHeader:     "X-Auth-User",
+	UserIDHeader:   "X-Auth-User-Id",
+	ClientIDHeader: "X-Auth-Client-Id",
+	RolesHeader:    "X-Auth-Roles",
+	ExpiresHeader:  "X-Auth-Token-Expires",
+}
+
+func newForwardAuthRouter(t *testing.T, forwardAuthConfig config.ForwardAuthConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: &model.User{
+		Model:    gorm.Model{ID: 42},
+		Username: "alice",
+		Password: hashPassword("secret"),
+		Roles:    []model.Role{{Name: "editor"}, {Name: "admin"}},
+	}}
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.GET("/auth", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg), auth.AuthorizeHandler(), auth.ForwardAuthHandler(forwardAuthConfig))
+	return r
+}
+
+func TestForwardAuthHandler_SetsIdentityHeaders(t *testing.T) {
+	r := newForwardAuthRouter(t, defaultForwardAuthCfg)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := getWithToken(r, "/auth", token)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "alice", resp.Header().Get("X-Auth-User"))
+	assert.Equal(t, "42", resp.Header().Get("X-Auth-User-Id"))
+	assert.Equal(t, "admin,editor", resp.Header().Get("X-Auth-Roles"))
+
+	expires, err := strconv.ParseInt(resp.Header().Get("X-Auth-Token-Expires"), 10, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Now().Add(15*time.Minute).Unix(), expires, 5)
+}
+
+func TestForwardAuthHandler_ConfigurableHeaders(t *testing.T) {
+	r := newForwardAuthRouter(t, config.ForwardAuthConfig{UserHeader: "Remote-User"})
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := getWithToken(r, "/auth", token)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "alice", resp.Header().Get("Remote-User"))
+	assert.Empty(t, resp.Header().Get("X-Auth-User"))
+	assert.Empty(t, resp.Header().Get("X-Auth-Roles"))
+}
+
+func TestForwardAuthHandler_NoHeadersWhenRejected(t *testing.T) {
+	r := newForwardAuthRouter(t, defaultForwardAuthCfg)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := getWithToken(r, "/auth?role=superuser", token)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Empty(t, resp.Header().Get("X-Auth-User"))
+}
@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"Auth/internal/signing"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func JWKSHandler(keys *signing.KeyRing) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, keys.JWKS())
+	}
+}
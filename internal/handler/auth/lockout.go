@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/identity"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Failed logins are counted per account and per client IP. The account
+// counter keeps one client from guessing a single password, the IP counter
+// keeps it from trying one password against many accounts. Unknown
+// usernames are counted like existing ones, so that a lock does not reveal
+// which accounts exist.
+
+// loginLockedFor returns how long logins of the username from the ip are
+// still locked, 0 when they are not.
+func loginLockedFor(cache Cache, lockout config.LockoutConfig, username, ip string) time.Duration {
+	var remaining time.Duration
+	if lockout.AccountAttempts > 0 {
+		remaining = lockRemaining(cache, "user:"+username)
+	}
+	if lockout.IPAttempts > 0 {
+		remaining = max(remaining, lockRemaining(cache, "ip:"+ip))
+	}
+	return remaining
+}
+
+// recordLoginFailure counts a failed login and locks the account or the ip
+// once it reaches its number of attempts.
+func recordLoginFailure(cache Cache, lockout config.LockoutConfig, username, ip string) {
+	window := time.Duration(lockout.WindowMinutes) * time.Minute
+	lock := time.Duration(lockout.LockMinutes) * time.Minute
+
+	if lockout.AccountAttempts > 0 {
+		countLoginFailure(cache, "user:"+username, lockout.AccountAttempts, window, lock)
+	}
+	if lockout.IPAttempts > 0 {
+		countLoginFailure(cache, "ip:"+ip, lockout.IPAttempts, window, lock)
+	}
+}
+
+// resetLoginFailures forgets the failures of an account after a successful
+// login. The IP counter is left alone, otherwise an attacker could reset it
+// by logging into an account of their own between guesses.
+func resetLoginFailures(cache Cache, lockout config.LockoutConfig, username string) {
+	if lockout.AccountAttempts > 0 {
+		_ = cache.Delete("login_failures:user:" + username)
+	}
+}
+
+func countLoginFailure(cache Cache, subject string, attempts int, window, lock time.Duration) {
+	failures, err := cache.Increment("login_failures:"+subject, window)
+	if err != nil || failures < int64(attempts) {
+		return
+	}
+
+	lockedUntil := time.Now().Add(lock)
+	_ = cache.Set("login_locked:"+subject, lockedUntil.Unix(), lock)
+	_ = cache.Delete("login_failures:" + subject)
+}
+
+func lockRemaining(cache Cache, subject string) time.Duration {
+	val, err := cache.Get("login_locked:" + subject)
+	if err != nil || val == "" {
+		return 0
+	}
+	lockedUntil, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return max(time.Until(time.Unix(lockedUntil, 0)), 0)
+}
+
+// respondLocked answers a login attempt while it is locked.
+func respondLocked(ctx *gin.Context, remaining time.Duration) {
+	ctx.Header("Retry-After", retryAfter(remaining))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{"error": "too many failed login attempts, try again later"})
+}
+
+// retryAfter formats a duration as the seconds of a Retry-After header.
+func retryAfter(remaining time.Duration) string {
+	return strconv.Itoa(max(int(math.Ceil(remaining.Seconds())), 1))
+}
+
+// LockoutStatusHandler shows the failed logins of an account
+// (/admin/lockouts/users/:username) or a client IP (/admin/lockouts/ips/:ip).
+func LockoutStatusHandler(cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		subject := lockoutSubject(ctx)
+
+		failures := 0
+		if val, err := cache.Get("login_failures:" + subject); err == nil {
+			failures, _ = strconv.Atoi(val)
+		}
+
+		status := gin.H{"failures": failures, "locked": false}
+		if remaining := lockRemaining(cache, subject); remaining > 0 {
+			status["locked"] = true
+			status["locked_until"] = time.Now().Add(remaining).Truncate(time.Second)
+		}
+
+		ctx.JSON(http.StatusOK, status)
+	}
+}
+
+// UnlockHandler lifts the lock of an account or a client IP and resets its
+// failed logins.
+func UnlockHandler(cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		subject := lockoutSubject(ctx)
+
+		if err := cache.Delete("login_locked:" + subject); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not unlock"})
+			return
+		}
+		_ = cache.Delete("login_failures:" + subject)
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "unlocked"})
+	}
+}
+
+func lockoutSubject(ctx *gin.Context) string {
+	if ip := ctx.Param("ip"); ip != "" {
+		return "ip:" + ip
+	}
+	return "user:" + identity.NormalizeUsername(ctx.Param("username"))
+}
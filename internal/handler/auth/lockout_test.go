@@ -0,0 +1,118 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lockoutCfg = config.LockoutConfig{
+	AccountAttempts: 3,
+	IPAttempts:      5,
+	WindowMinutes:   15,
+	LockMinutes:     15,
+}
+
+func newLockoutRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: policyAdmin()}
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.GET("/admin/lockouts/users/:username", authenticated, auth.RequireRole("admin"), auth.LockoutStatusHandler(cache))
+	r.DELETE("/admin/lockouts/users/:username", authenticated, auth.RequireRole("admin"), auth.UnlockHandler(cache))
+	r.DELETE("/admin/lockouts/ips/:ip", authenticated, auth.RequireRole("admin"), auth.UnlockHandler(cache))
+	return r
+}
+
+func attemptLogin(r *gin.Engine, username, password, ip string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(auth.AuthInput{Username: username, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func adminRequest(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestLockout_AccountIsLockedAfterFailedAttempts(t *testing.T) {
+	r := newLockoutRouter(t)
+
+	for i := 0; i < lockoutCfg.AccountAttempts; i++ {
+		resp := attemptLogin(r, "alice", "wrong", "10.0.0.1")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	}
+
+	resp := attemptLogin(r, "alice", "secret", "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.NotEmpty(t, resp.Header().Get("Retry-After"))
+}
+
+func TestLockout_SuccessResetsAccountFailures(t *testing.T) {
+	r := newLockoutRouter(t)
+
+	for i := 0; i < lockoutCfg.AccountAttempts-1; i++ {
+		attemptLogin(r, "alice", "wrong", "10.0.0.1")
+	}
+	require.Equal(t, http.StatusOK, attemptLogin(r, "alice", "secret", "10.0.0.1").Code)
+
+	for i := 0; i < lockoutCfg.AccountAttempts-1; i++ {
+		attemptLogin(r, "alice", "wrong", "10.0.0.1")
+	}
+	assert.Equal(t, http.StatusOK, attemptLogin(r, "alice", "secret", "10.0.0.1").Code)
+}
+
+func TestLockout_IPIsLockedAcrossAccounts(t *testing.T) {
+	r := newLockoutRouter(t)
+
+	for i, username := range []string{"bob", "carol", "dave", "erin", "frank"} {
+		resp := attemptLogin(r, username, "guess", "10.0.0.1")
+		assert.Equal(t, http.StatusUnauthorized, resp.Code, "attempt %d", i)
+	}
+
+	assert.Equal(t, http.StatusTooManyRequests, attemptLogin(r, "alice", "secret", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, attemptLogin(r, "alice", "secret", "10.0.0.2").Code)
+}
+
+func TestLockout_AdminSeesAndLiftsLock(t *testing.T) {
+	r := newLockoutRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	for i := 0; i < lockoutCfg.AccountAttempts; i++ {
+		attemptLogin(r, "mallory", "wrong", "10.0.0.1")
+	}
+
+	resp := adminRequest(r, http.MethodGet, "/admin/lockouts/users/mallory", token)
+	require.Equal(t, http.StatusOK, resp.Code)
+	var status map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, true, status["locked"])
+	assert.NotEmpty(t, status["locked_until"])
+
+	resp = adminRequest(r, http.MethodDelete, "/admin/lockouts/users/mallory", token)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	resp = adminRequest(r, http.MethodGet, "/admin/lockouts/users/mallory", token)
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	assert.Equal(t, false, status["locked"])
+	assert.Equal(t, float64(0), status["failures"])
+}
@@ -2,11 +2,10 @@ package auth
 
 import (
 	"Auth/config"
-	"context"
+	"Auth/internal/identity"
+	"Auth/internal/signing"
 	"net/http"
-	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -14,6 +13,10 @@ import (
 func LoginHandler(
 	repo UserFindByUsernameRepository,
 	tokenConfig config.JWTConfig,
+	lockout config.LockoutConfig,
+	emailConfig config.EmailVerificationConfig,
+	sessionConfig config.SessionConfig,
+	keys *signing.KeyRing,
 	cache Cache,
 ) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -22,47 +25,52 @@ func LoginHandler(
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "corrupted input payload"})
 			return
 		}
-
-		var hashedPassword string
-		foundInCache := false
-
-		if val, err := cache.Get(input.Username); err == nil {
-			hashedPassword = val
-			foundInCache = true
+		if input.Session && !sessionConfig.Enabled {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "session login is disabled"})
+			return
 		}
 
-		if !foundInCache {
-			reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
-			defer cancel()
-
-			user, err := repo.FindByUsername(reqCtx, input.Username)
-			if err != nil {
-				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
-				return
-			}
-
-			hashedPassword = user.Password
+		username := identity.NormalizeUsername(input.Username)
+		if remaining := loginLockedFor(cache, lockout, username, ctx.ClientIP()); remaining > 0 {
+			respondLocked(ctx, remaining)
+			return
+		}
 
-			_ = cache.Set(user.Username, user.Password, 5*time.Minute)
+		user, err := findUser(ctx.Request.Context(), repo, cache, username)
+		if err != nil {
+			recordLoginFailure(cache, lockout, username, ctx.ClientIP())
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
 		}
 
-		if bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(input.Password)) != nil {
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)) != nil {
+			recordLoginFailure(cache, lockout, username, ctx.ClientIP())
 			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
+		resetLoginFailures(cache, lockout, username)
 
-		claims := jwt.MapClaims{
-			"username": input.Username,
-			"exp":      time.Now().Add(time.Duration(tokenConfig.ExpirationMinutes) * time.Minute).Unix(),
+		if err := accountStatusError(user); err != nil {
+			respondAccountStatus(ctx, err)
+			return
 		}
 
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		tokenString, err := token.SignedString([]byte(tokenConfig.Secret))
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		if emailConfig.Required && !user.EmailVerified {
+			respondAccountStatus(ctx, errAccountPendingVerification)
+			return
+		}
+
+		if user.TOTPEnabled {
+			mfaToken, err := generateMFAToken(keys, user)
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+				return
+			}
+
+			ctx.JSON(http.StatusOK, gin.H{"mfa_required": true, "mfa_token": mfaToken})
 			return
 		}
 
-		ctx.JSON(http.StatusOK, gin.H{"token": tokenString})
+		respondLogin(ctx, tokenConfig, sessionConfig, keys, cache, user, input.Session)
 	}
 }
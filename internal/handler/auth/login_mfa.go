@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/signing"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginMFAHandler is the second login step for users with MFA enabled. It
+// exchanges the mfa_token returned by LoginHandler together with a TOTP code
+// or a recovery code for the regular token pair. Every mfa_token allows a
+// single attempt, after a wrong code the login has to start over.
+func LoginMFAHandler(
+	repo UserPasswordRepository,
+	tokenConfig config.JWTConfig,
+	sessionConfig config.SessionConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input LoginMFAInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.MFAToken == "" || (input.Code == "" && input.RecoveryCode == "") {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "corrupted input payload"})
+			return
+		}
+		if input.Session && !sessionConfig.Enabled {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "session login is disabled"})
+			return
+		}
+
+		claims, err := parseMFAToken(keys, input.MFAToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+			return
+		}
+
+		jti, _ := claims["jti"].(string)
+		if first, err := cache.SetNX("mfa_token_used:"+jti, "used", mfaTokenTTL); err != nil || !first {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+			return
+		}
+
+		username, _ := claims["sub"].(string)
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, username)
+		if err != nil || !user.TOTPEnabled || !sameAccount(user, userIDClaim(claims), tokenVersion(claims)) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa token"})
+			return
+		}
+
+		if err := accountStatusError(user); err != nil {
+			respondAccountStatus(ctx, err)
+			return
+		}
+
+		if input.Code != "" {
+			if !verifyTOTPCode(cache, user.Username, user.TOTPSecret, input.Code) {
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa code"})
+				return
+			}
+		} else {
+			if !user.UseRecoveryCode(input.RecoveryCode) {
+				ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid recovery code"})
+				return
+			}
+			if err := repo.Update(user); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not use recovery code"})
+				return
+			}
+			_ = forgetUser(cache, user.Username)
+		}
+
+		respondLogin(ctx, tokenConfig, sessionConfig, keys, cache, user, input.Session)
+	}
+}
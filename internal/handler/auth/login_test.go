@@ -23,6 +23,15 @@ func hashPassword(password string) string {
 	return string(hashed)
 }
 
+func cachedUser(t *testing.T, username, hashedPassword string) string {
+	t.Helper()
+	userJSON, err := json.Marshal(model.User{Username: username, Password: hashedPassword})
+	if err != nil {
+		t.Fatalf("failed to marshal user: %v", err)
+	}
+	return string(userJSON)
+}
+
 func TestLoginHandler_CacheHit(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -31,7 +40,8 @@ func TestLoginHandler_CacheHit(t *testing.T) {
 
 	cache := &MockCacheRepository{
 		GetFunc: func(key string) (string, error) {
-			return hashed, nil
+			assert.Equal(t, "user:cacheduser", key)
+			return cachedUser(t, "cacheduser", hashed), nil
 		},
 	}
 	repo := &MockUserRepository{}
@@ -49,7 +59,7 @@ func TestLoginHandler_CacheHit(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	r := gin.New()
-	r.POST("/login", auth.LoginHandler(repo, tokenCfg, cache))
+	r.POST("/login", auth.LoginHandler(repo, tokenCfg, config.LockoutConfig{}, emailCfg, sessionCfg, newTestKeyRing(t, testSecret), cache))
 	r.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusOK, resp.Code)
@@ -91,7 +101,7 @@ func TestLoginHandler_CacheMissAndDBHit(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	r := gin.New()
-	r.POST("/login", auth.LoginHandler(repo, tokenCfg, cache))
+	r.POST("/login", auth.LoginHandler(repo, tokenCfg, config.LockoutConfig{}, emailCfg, sessionCfg, newTestKeyRing(t, testSecret), cache))
 	r.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusOK, resp.Code)
@@ -101,7 +111,7 @@ func TestLoginHandler_CacheMissAndDBHit(t *testing.T) {
 func TestLoginHandler_CacheWrongPassword(t *testing.T) {
 	cache := &MockCacheRepository{
 		GetFunc: func(key string) (string, error) {
-			return hashPassword("correctpassword"), nil
+			return cachedUser(t, "user", hashPassword("correctpassword")), nil
 		},
 	}
 
@@ -120,7 +130,7 @@ func TestLoginHandler_CacheWrongPassword(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	r := gin.New()
-	r.POST("/login", auth.LoginHandler(repo, tokenCfg, cache))
+	r.POST("/login", auth.LoginHandler(repo, tokenCfg, config.LockoutConfig{}, emailCfg, sessionCfg, newTestKeyRing(t, testSecret), cache))
 	r.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusUnauthorized, resp.Code)
@@ -137,7 +147,7 @@ func TestLoginHandler_InvalidJSON(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	r := gin.New()
-	r.POST("/login", auth.LoginHandler(repo, tokenCfg, cache))
+	r.POST("/login", auth.LoginHandler(repo, tokenCfg, config.LockoutConfig{}, emailCfg, sessionCfg, newTestKeyRing(t, testSecret), cache))
 	r.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
@@ -167,7 +177,7 @@ func TestLoginHandler_UserNotFoundInCacheAndDB(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	r := gin.New()
-	r.POST("/login", auth.LoginHandler(repo, tokenCfg, cache))
+	r.POST("/login", auth.LoginHandler(repo, tokenCfg, config.LockoutConfig{}, emailCfg, sessionCfg, newTestKeyRing(t, testSecret), cache))
 	r.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusUnauthorized, resp.Code)
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"Auth/config"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func LogoutHandler(tokenConfig config.JWTConfig, sessionConfig config.SessionConfig, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input RefreshInput
+		_ = ctx.ShouldBindJSON(&input)
+
+		if sessionID := ctx.GetString("session_id"); sessionID != "" {
+			if err := endSession(ctx, tokenConfig, sessionConfig, cache, sessionID); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not end session"})
+				return
+			}
+		}
+
+		if err := revokeCurrentToken(ctx, cache); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke token"})
+			return
+		}
+
+		if input.RefreshToken != "" {
+			if err := revokeRefreshToken(cache, input.RefreshToken, refreshTokenTTL(tokenConfig)); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke refresh token"})
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "logged out"})
+	}
+}
+
+// revokeCurrentToken blacklists the token AuthHandler authenticated the
+// request with, for as long as the token would otherwise stay valid.
+func revokeCurrentToken(ctx *gin.Context, cache Cache) error {
+	return blacklistToken(cache, ctx.GetString("token_id"), ctx.GetTime("token_expires_at"))
+}
+
+// blacklistToken makes AuthHandler refuse the token until it expires anyway.
+func blacklistToken(cache Cache, tokenID string, expiresAt time.Time) error {
+	if tokenID == "" || expiresAt.IsZero() {
+		return nil
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	return cache.Set(blacklistKey(tokenID), "invalid", ttl)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogoutAllHandler bumps the token version of the user, which invalidates
+// every access and refresh token and every session issued to them so far.
+func LogoutAllHandler(repo UserTokenVersionRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.GetString("username")
+		if username == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		if err := logoutEverywhere(repo, cache, username); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke tokens"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "logged out everywhere"})
+	}
+}
+
+// logoutEverywhere revokes every token and session of the user.
+func logoutEverywhere(repo UserTokenVersionRepository, cache Cache, username string) error {
+	if err := repo.IncrementTokenVersion(username); err != nil {
+		return err
+	}
+	if err := forgetUser(cache, username); err != nil {
+		return err
+	}
+
+	dropUserSessions(cache, username)
+	return nil
+}
+
+// dropUserSessions drops every session of the user once a token version bump
+// invalidated them, so that they leave the user's list.
+func dropUserSessions(cache Cache, username string) {
+	if sessions, err := listSessions(cache, username); err == nil {
+		for _, session := range sessions {
+			_ = dropSession(cache, session)
+		}
+	}
+}
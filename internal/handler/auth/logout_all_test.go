@@ -0,0 +1,71 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogoutAllRouter(t *testing.T, repo *MockUserRepository, cache *MockCacheRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	keys := newTestKeyRing(t, testSecret)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/token/refresh", auth.RefreshTokenHandler(repo, refreshTokenCfg, keys, cache))
+	r.POST("/logout/all", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg), auth.LogoutAllHandler(repo, cache))
+	r.GET("/auth", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg))
+	return r
+}
+
+func postLogoutAll(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/logout/all", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestLogoutAllHandler_RevokesEverySession(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newLogoutAllRouter(t, repo, cache)
+
+	first := login(t, r, "alice", "secret")
+	second := login(t, r, "alice", "secret")
+
+	resp := postLogoutAll(r, first["token"].(string))
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "logged out everywhere")
+
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, first["token"].(string)).Code)
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, second["token"].(string)).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(r, second["refresh_token"].(string)).Code)
+
+	fresh := login(t, r, "alice", "secret")
+	assert.Equal(t, http.StatusOK, getAuth(r, fresh["token"].(string)).Code)
+}
+
+func TestLogoutAllHandler_RepositoryError(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{
+		User: &model.User{Username: "alice", Password: hashPassword("secret")},
+		IncrementTokenVersionFunc: func(username string) error {
+			return errors.New("db down")
+		},
+	}
+	r := newLogoutAllRouter(t, repo, cache)
+
+	pair := login(t, r, "alice", "secret")
+
+	resp := postLogoutAll(r, pair["token"].(string))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Equal(t, http.StatusOK, getAuth(r, pair["token"].(string)).Code)
+}
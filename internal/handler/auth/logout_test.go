@@ -0,0 +1,126 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLogoutRouter(t *testing.T, cache *MockCacheRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/token/refresh", auth.RefreshTokenHandler(repo, refreshTokenCfg, keys, cache))
+	r.POST("/logout", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg), auth.LogoutHandler(refreshTokenCfg, sessionCfg, cache))
+	r.GET("/auth", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg))
+	return r
+}
+
+func postLogout(r *gin.Engine, token string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func getAuth(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestLogoutHandler_RevokesTokenByJTI(t *testing.T) {
+	cache, store := newInMemoryCache()
+	r := newLogoutRouter(t, cache)
+
+	pair := login(t, r, "alice", "secret")
+	token := pair["token"].(string)
+	require.Equal(t, http.StatusOK, getAuth(r, token).Code)
+
+	resp := postLogout(r, token, gin.H{})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "logged out")
+
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	assert.Contains(t, store, "jwt_blacklist:"+claims["jti"].(string))
+	for key := range store {
+		assert.False(t, strings.Contains(key, token), "blacklist must not be keyed by the raw token")
+	}
+
+	resp = getAuth(r, token)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "token is blacklisted")
+}
+
+func TestLogoutHandler_OtherSessionsStayValid(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newLogoutRouter(t, cache)
+
+	first := login(t, r, "alice", "secret")["token"].(string)
+	second := login(t, r, "alice", "secret")["token"].(string)
+
+	require.Equal(t, http.StatusOK, postLogout(r, first, gin.H{}).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, first).Code)
+	assert.Equal(t, http.StatusOK, getAuth(r, second).Code)
+}
+
+func TestLogoutHandler_RevokesRefreshToken(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newLogoutRouter(t, cache)
+
+	pair := login(t, r, "alice", "secret")
+	resp := postLogout(r, pair["token"].(string), auth.RefreshInput{RefreshToken: pair["refresh_token"].(string)})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp = refresh(r, pair["refresh_token"].(string))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestLogoutHandler_BlacklistExpiresWithToken(t *testing.T) {
+	var ttl time.Duration
+	cache := &MockCacheRepository{
+		SetFunc: func(key string, value any, expiration time.Duration) error {
+			if strings.HasPrefix(key, "jwt_blacklist:") {
+				ttl = expiration
+			}
+			return nil
+		},
+	}
+
+	claims := jwt.MapClaims{
+		"username": "alice",
+		"jti":      "jti-1",
+		"exp":      time.Now().Add(3 * time.Minute).Unix(),
+	}
+	token := generateToken(t, claims, testSecret)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/logout", auth.AuthHandler(newTestKeyRing(t, testSecret), cache, newAnyUserRepository(), nil, nil, sessionCfg), auth.LogoutHandler(refreshTokenCfg, sessionCfg, cache))
+
+	resp := postLogout(r, token, gin.H{})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.InDelta(t, float64(3*time.Minute), float64(ttl), float64(5*time.Second))
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const recoveryCodeCount = 10
+
+// TOTPEnrollHandler generates a new TOTP secret for the user. The secret is
+// only stored as pending, MFA is enabled once TOTPVerifyHandler sees a valid
+// code for it.
+func TOTPEnrollHandler(repo UserPasswordRepository, mfaConfig config.MFAConfig, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.GetString("username")
+		if username == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, username)
+		if err != nil {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "user not found"})
+			return
+		}
+
+		if user.TOTPEnabled {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "mfa already enabled"})
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate secret"})
+			return
+		}
+
+		user.TOTPSecret = secret
+		if err := repo.Update(user); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not save secret"})
+			return
+		}
+		_ = forgetUser(cache, username)
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(mfaConfig.Issuer, username, secret),
+		})
+	}
+}
+
+// TOTPVerifyHandler confirms the enrollment with a code from the
+// authenticator app, enables MFA and hands out the recovery codes. The codes
+// are shown only this once, the user record keeps just their hashes.
+func TOTPVerifyHandler(repo UserPasswordRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.GetString("username")
+		if username == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		var input TOTPCodeInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Code == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, username)
+		if err != nil {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "user not found"})
+			return
+		}
+
+		if user.TOTPEnabled {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "mfa already enabled"})
+			return
+		}
+
+		if user.TOTPSecret == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "mfa enrollment not started"})
+			return
+		}
+
+		if _, ok := totp.Validate(user.TOTPSecret, input.Code, time.Now()); !ok {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid mfa code"})
+			return
+		}
+
+		codes, err := newRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate recovery codes"})
+			return
+		}
+
+		user.TOTPEnabled = true
+		user.SetRecoveryCodes(codes)
+		if err := repo.Update(user); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not enable mfa"})
+			return
+		}
+		_ = forgetUser(cache, username)
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "mfa enabled", "recovery_codes": codes})
+	}
+}
+
+// verifyTOTPCode checks the code and makes sure it was not used before, a
+// code stays valid for a few periods and must not be replayable within them.
+func verifyTOTPCode(cache Cache, username, secret, code string) bool {
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	ttl := time.Duration(2*totp.Skew+1) * totp.Period
+	first, err := cache.SetNX(fmt.Sprintf("totp_used:%s:%d", username, step), "used", ttl)
+	return err == nil && first
+}
+
+func newRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		b := make([]byte, 6)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
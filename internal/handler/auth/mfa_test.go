@@ -0,0 +1,192 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"Auth/internal/totp"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMFARouter(t *testing.T, repo *MockUserRepository, cache *MockCacheRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	keys := newTestKeyRing(t, testSecret)
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/login/mfa", auth.LoginMFAHandler(repo, refreshTokenCfg, sessionCfg, keys, cache))
+	r.POST("/mfa/totp/enroll", authenticated, auth.TOTPEnrollHandler(repo, config.MFAConfig{Issuer: "Auth"}, cache))
+	r.POST("/mfa/totp/verify", authenticated, auth.TOTPVerifyHandler(repo, cache))
+	r.GET("/auth", authenticated)
+	return r
+}
+
+func postAuthenticated(r *gin.Engine, path, token string, input any) *httptest.ResponseRecorder {
+	req := newJSONRequest(http.MethodPost, path, input)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+// enrollMFA enables MFA for alice and returns her TOTP secret and recovery codes.
+func enrollMFA(t *testing.T, r *gin.Engine) (string, []string) {
+	t.Helper()
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := postAuthenticated(r, "/mfa/totp/enroll", token, nil)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var enrollment struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &enrollment))
+	assert.Contains(t, enrollment.OtpauthURI, "otpauth://totp/Auth:alice")
+
+	resp = postAuthenticated(r, "/mfa/totp/verify", token, auth.TOTPCodeInput{Code: currentCode(t, enrollment.Secret)})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var verification struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &verification))
+	require.Len(t, verification.RecoveryCodes, 10)
+
+	return enrollment.Secret, verification.RecoveryCodes
+}
+
+func mfaToken(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+	out := login(t, r, "alice", "secret")
+	require.Equal(t, true, out["mfa_required"])
+	assert.Nil(t, out["token"])
+	return out["mfa_token"].(string)
+}
+
+func TestMFA_LoginWithTOTP(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	secret, _ := enrollMFA(t, r)
+	assert.True(t, repo.User.TOTPEnabled)
+
+	pending := mfaToken(t, r)
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, pending).Code)
+
+	resp := postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: pending, Code: currentCode(t, secret)})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var pair map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &pair))
+	assert.Equal(t, http.StatusOK, getAuth(r, pair["token"].(string)).Code)
+
+	resp = postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: pending, Code: currentCode(t, secret)})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid mfa token")
+}
+
+func TestMFA_CodeCannotBeReplayed(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	secret, _ := enrollMFA(t, r)
+	code := currentCode(t, secret)
+
+	resp := postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: mfaToken(t, r), Code: code})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	resp = postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: mfaToken(t, r), Code: code})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid mfa code")
+}
+
+func TestMFA_WrongCode(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	enrollMFA(t, r)
+
+	resp := postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: mfaToken(t, r), Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestMFA_RecoveryCodesAreSingleUse(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	_, recoveryCodes := enrollMFA(t, r)
+	for _, stored := range repo.User.RecoveryCodes {
+		assert.NotContains(t, recoveryCodes, stored)
+	}
+
+	resp := postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: mfaToken(t, r), RecoveryCode: recoveryCodes[0]})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Len(t, repo.User.RecoveryCodes, 9)
+
+	resp = postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: mfaToken(t, r), RecoveryCode: recoveryCodes[0]})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid recovery code")
+}
+
+func TestMFA_VerifyRejectsWrongCode(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	token := login(t, r, "alice", "secret")["token"].(string)
+	require.Equal(t, http.StatusOK, postAuthenticated(r, "/mfa/totp/enroll", token, nil).Code)
+
+	resp := postAuthenticated(r, "/mfa/totp/verify", token, auth.TOTPCodeInput{Code: "000000"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.False(t, repo.User.TOTPEnabled)
+}
+
+func TestMFA_EnrollWhenAlreadyEnabled(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	secret, _ := enrollMFA(t, r)
+
+	resp := postJSON(r, "/login/mfa", auth.LoginMFAInput{MFAToken: mfaToken(t, r), Code: currentCode(t, secret)})
+	require.Equal(t, http.StatusOK, resp.Code)
+	var pair map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &pair))
+
+	resp = postAuthenticated(r, "/mfa/totp/enroll", pair["token"].(string), nil)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+	assert.Equal(t, secret, repo.User.TOTPSecret)
+}
+
+func TestMFA_SecretsStayOutOfTheUserCache(t *testing.T) {
+	cache, store := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newMFARouter(t, repo, cache)
+
+	secret, _ := enrollMFA(t, r)
+	mfaToken(t, r)
+
+	cached := store["user:alice"]
+	require.NotEmpty(t, cached)
+	assert.NotContains(t, cached, secret)
+	assert.NotContains(t, cached, repo.User.RecoveryCodes[0])
+	assert.Contains(t, cached, `"totp_enabled":true`)
+}
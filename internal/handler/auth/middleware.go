@@ -1,8 +1,11 @@
 package auth
 
 import (
+	"Auth/config"
+	"Auth/internal/signing"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,9 +13,29 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthHandler(secret string, cache Cache) gin.HandlerFunc {
+// AuthHandler authenticates the request with a Bearer token, when apiKeys
+// is set with an API key and, when sessions are enabled and no Authorization
+// header is sent, with a session cookie. Tokens of the client credentials
+// grant are only accepted when clients is set.
+func AuthHandler(
+	keys *signing.KeyRing,
+	cache Cache,
+	repo UserFindByUsernameRepository,
+	apiKeys APIKeyFindRepository,
+	clients ClientFindRepository,
+	sessionConfig config.SessionConfig,
+) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		if key := apiKeyFromRequest(ctx); key != "" {
+			authenticateAPIKey(ctx, apiKeys, repo, cache, key)
+			return
+		}
+
 		tokenString := ctx.GetHeader("Authorization")
+		if session := sessionCookie(ctx, sessionConfig); tokenString == "" && session != "" {
+			authenticateSession(ctx, sessionConfig, cache, repo, session)
+			return
+		}
 		if tokenString == "" {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
 			return
@@ -24,51 +47,141 @@ func AuthHandler(secret string, cache Cache) gin.HandlerFunc {
 		}
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			if secret == "" {
-				return nil, fmt.Errorf("secret cannot be empty")
-			}
-			return []byte(secret), nil
-		})
-
+		token, err := verifyAccessToken(ctx.Request.Context(), keys, cache, repo, clients, tokenString)
 		if err != nil {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
 
-		blacklistKey := fmt.Sprintf("jwt_blacklist:%s", tokenString)
-		if cache != nil {
-			if val, err := Cache.Get(cache, blacklistKey); err == nil && val != "" {
-				ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token is blacklisted"})
-				return
-			}
+		// Clients act with their scopes where users have permissions.
+		if token.User == nil {
+			ctx.Set("client_id", token.ClientID)
+			ctx.Set("scopes", token.Scopes)
+			ctx.Set("roles", []string{})
+			ctx.Set("permissions", token.Scopes)
+		} else {
+			ctx.Set("username", token.User.Username)
+			ctx.Set("user_id", token.User.ID)
+			ctx.Set("roles", stringsClaim(token.Claims, "roles"))
+			ctx.Set("permissions", stringsClaim(token.Claims, "permissions"))
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || !token.Valid {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
-			return
+		if token.SessionID != "" {
+			ctx.Set("session_id", token.SessionID)
+			touchTokenSession(ctx, cache, token.SessionID)
 		}
+		setTokenContext(ctx, token.Claims, token.ID)
 
-		if err := validateTokenClaims(claims); err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token validation failed: " + err.Error()})
-			return
-		}
+		ctx.Next()
+	}
+}
 
-		if username, exists := claims["username"]; exists {
-			ctx.Set("username", username)
-		} else {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "username claim required"})
-			return
-		}
+// authenticateAPIKey sets the same context as a token of the key's user. A
+// key with scopes is limited to those of the user's permissions and has no
+// roles.
+func authenticateAPIKey(ctx *gin.Context, apiKeys APIKeyFindRepository, repo UserFindByUsernameRepository, cache Cache, key string) {
+	apiKey, user, err := verifyAPIKey(ctx.Request.Context(), apiKeys, repo, cache, key)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-		ctx.Next()
+	roles, permissions := user.RoleNames(), user.Permissions()
+	if len(apiKey.Scopes) > 0 {
+		roles = []string{}
+		permissions = slices.DeleteFunc(slices.Clone(apiKey.Scopes), func(scope string) bool {
+			return !slices.Contains(permissions, scope)
+		})
+	}
+
+	ctx.Set("username", user.Username)
+	ctx.Set("user_id", user.ID)
+	ctx.Set("api_key_id", apiKey.ID)
+	ctx.Set("roles", roles)
+	ctx.Set("permissions", permissions)
+	if apiKey.ExpiresAt != nil {
+		ctx.Set("token_expires_at", *apiKey.ExpiresAt)
+	}
+
+	ctx.Next()
+}
+
+// authenticateSession sets the same context as a token of the session's
+// user would. Non-safe requests also have to pass the CSRF check.
+func authenticateSession(ctx *gin.Context, sessionConfig config.SessionConfig, cache Cache, repo UserFindByUsernameRepository, token string) {
+	session, user, err := touchSession(ctx, sessionConfig, cache, repo, token)
+	if isCSRFError(err) {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		clearSessionCookie(ctx, sessionConfig)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Set("username", user.Username)
+	ctx.Set("user_id", user.ID)
+	ctx.Set("session_id", session.ID)
+	ctx.Set("roles", user.RoleNames())
+	ctx.Set("permissions", user.Permissions())
+	ctx.Set("token_expires_at", session.expiresAt(sessionConfig))
+
+	ctx.Next()
+}
+
+func setTokenContext(ctx *gin.Context, claims jwt.MapClaims, tokenID string) {
+	ctx.Set("token_id", tokenID)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		ctx.Set("token_expires_at", exp.Time)
 	}
 }
 
+// tokenIDFromClaims returns the jti of the token. Tokens issued before jti
+// was introduced are identified by their raw string instead.
+func tokenIDFromClaims(claims jwt.MapClaims, tokenString string) string {
+	if jti, ok := claims["jti"].(string); ok && jti != "" {
+		return jti
+	}
+	return tokenString
+}
+
+// tokenVersion reads the ver claim, tokens issued before it was introduced
+// count as version 0.
+func tokenVersion(claims jwt.MapClaims) int {
+	if ver, ok := claims["ver"].(float64); ok {
+		return int(ver)
+	}
+	return 0
+}
+
+func userIDClaim(claims jwt.MapClaims) uint {
+	if uid, ok := claims["uid"].(float64); ok && uid >= 0 {
+		return uint(uid)
+	}
+	return 0
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	values := []string{}
+	if list, ok := claims[name].([]any); ok {
+		for _, value := range list {
+			if str, ok := value.(string); ok {
+				values = append(values, str)
+			}
+		}
+	}
+	return values
+}
+
+func blacklistKey(tokenID string) string {
+	return fmt.Sprintf("jwt_blacklist:%s", tokenID)
+}
+
 func validateTokenClaims(claims jwt.MapClaims) error {
 	now := time.Now()
 
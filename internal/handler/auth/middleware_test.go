@@ -2,6 +2,8 @@ package auth_test
 
 import (
 	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"Auth/internal/signing"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -24,6 +26,19 @@ func generateToken(t *testing.T, claims jwt.MapClaims, secret string) string {
 	return tokenString
 }
 
+func newTestKeyRing(t *testing.T, secret string) *signing.KeyRing {
+	t.Helper()
+	keys, err := signing.NewHMACKeyRing(secret)
+	if err != nil {
+		t.Fatalf("failed to create key ring: %v", err)
+	}
+	return keys
+}
+
+func newTestAuthHandler(_ *testing.T, keys *signing.KeyRing) gin.HandlerFunc {
+	return auth.AuthHandler(keys, &MockCacheRepository{}, newAnyUserRepository(), nil, nil, sessionCfg)
+}
+
 func performRequest(_ *testing.T, handler gin.HandlerFunc, token string) *httptest.ResponseRecorder {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -42,13 +57,13 @@ func performRequest(_ *testing.T, handler gin.HandlerFunc, token string) *httpte
 }
 
 func TestAuthHandler_MissingAuthorizationHeader(t *testing.T) {
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "")
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "")
 	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "authorization header required")
 }
 
 func TestAuthHandler_InvalidTokenFormat(t *testing.T) {
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "Token abc.def.ghi")
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "Token abc.def.ghi")
 	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "invalid token format")
 }
@@ -59,7 +74,7 @@ func TestAuthHandler_InvalidSignature(t *testing.T) {
 		"exp":      time.Now().Add(5 * time.Minute).Unix(),
 	}
 	token := generateToken(t, claims, "wrong-secret")
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "Bearer "+token)
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "Bearer "+token)
 	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "signature is invalid")
 }
@@ -70,7 +85,7 @@ func TestAuthHandler_ExpiredToken(t *testing.T) {
 		"exp":      time.Now().Add(-5 * time.Minute).Unix(),
 	}
 	token := generateToken(t, claims, testSecret)
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "Bearer "+token)
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "Bearer "+token)
 	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "token is expired")
 }
@@ -82,7 +97,7 @@ func TestAuthHandler_NotBeforeInFuture(t *testing.T) {
 		"exp":      time.Now().Add(20 * time.Minute).Unix(),
 	}
 	token := generateToken(t, claims, testSecret)
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "Bearer "+token)
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "Bearer "+token)
 	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "token has invalid claims: token is not valid yet")
 }
@@ -92,7 +107,7 @@ func TestAuthHandler_MissingUsernameClaim(t *testing.T) {
 		"exp": time.Now().Add(5 * time.Minute).Unix(),
 	}
 	token := generateToken(t, claims, testSecret)
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "Bearer "+token)
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "Bearer "+token)
 	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "username claim required")
 }
@@ -104,7 +119,71 @@ func TestAuthHandler_ValidToken(t *testing.T) {
 		"nbf":      time.Now().Add(-5 * time.Minute).Unix(),
 	}
 	token := generateToken(t, claims, testSecret)
-	responseRecorder := performRequest(t, auth.AuthHandler(testSecret, &MockCacheRepository{}), "Bearer "+token)
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestKeyRing(t, testSecret)), "Bearer "+token)
 	assert.Equal(t, http.StatusOK, responseRecorder.Code)
 	assert.Contains(t, responseRecorder.Body.String(), "authorized")
 }
+
+func TestAuthHandler_AsymmetricToken(t *testing.T) {
+	keys := newTestES256KeyRing(t, "es-1")
+	token, err := keys.Sign(jwt.MapClaims{
+		"username": "validuser",
+		"exp":      time.Now().Add(5 * time.Minute).Unix(),
+	})
+	assert.NoError(t, err)
+
+	responseRecorder := performRequest(t, newTestAuthHandler(t, keys), "Bearer "+token)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+}
+
+func TestAuthHandler_UnknownKeyID(t *testing.T) {
+	token, err := newTestES256KeyRing(t, "es-other").Sign(jwt.MapClaims{
+		"username": "validuser",
+		"exp":      time.Now().Add(5 * time.Minute).Unix(),
+	})
+	assert.NoError(t, err)
+
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestES256KeyRing(t, "es-1")), "Bearer "+token)
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "unknown signing key")
+}
+
+func TestAuthHandler_HMACTokenRejectedByAsymmetricKey(t *testing.T) {
+	claims := jwt.MapClaims{
+		"username": "validuser",
+		"exp":      time.Now().Add(5 * time.Minute).Unix(),
+	}
+	token := generateToken(t, claims, testSecret)
+
+	responseRecorder := performRequest(t, newTestAuthHandler(t, newTestES256KeyRing(t, "es-1")), "Bearer "+token)
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "unexpected signing method")
+}
+
+func TestAuthHandler_UserNotFound(t *testing.T) {
+	claims := jwt.MapClaims{
+		"username": "ghost",
+		"exp":      time.Now().Add(5 * time.Minute).Unix(),
+	}
+	token := generateToken(t, claims, testSecret)
+	handler := auth.AuthHandler(newTestKeyRing(t, testSecret), &MockCacheRepository{}, &MockUserRepository{}, nil, nil, sessionCfg)
+
+	responseRecorder := performRequest(t, handler, "Bearer "+token)
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "user not found")
+}
+
+func TestAuthHandler_OutdatedTokenVersion(t *testing.T) {
+	claims := jwt.MapClaims{
+		"username": "validuser",
+		"ver":      1,
+		"exp":      time.Now().Add(5 * time.Minute).Unix(),
+	}
+	token := generateToken(t, claims, testSecret)
+	repo := &MockUserRepository{User: &model.User{Username: "validuser", TokenVersion: 2}}
+	handler := auth.AuthHandler(newTestKeyRing(t, testSecret), &MockCacheRepository{}, repo, nil, nil, sessionCfg)
+
+	responseRecorder := performRequest(t, handler, "Bearer "+token)
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), "token has been revoked")
+}
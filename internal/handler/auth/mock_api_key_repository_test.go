@@ -0,0 +1,62 @@
+package auth_test
+
+import (
+	"Auth/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+// MockAPIKeyRepository keeps keys in memory, User stands in for the user
+// the database would preload with each key.
+type MockAPIKeyRepository struct {
+	Keys []*model.APIKey
+	User *model.User
+}
+
+func (m *MockAPIKeyRepository) Create(key *model.APIKey) error {
+	key.ID = uint(len(m.Keys) + 1)
+	m.Keys = append(m.Keys, key)
+	return nil
+}
+
+func (m *MockAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	for _, key := range m.Keys {
+		if key.Hash == hash && !key.DeletedAt.Valid {
+			found := *key
+			found.User = m.User
+			return &found, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *MockAPIKeyRepository) ListByUser(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	keys := []model.APIKey{}
+	for _, key := range m.Keys {
+		if key.UserID == userID && !key.DeletedAt.Valid {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+func (m *MockAPIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	for _, key := range m.Keys {
+		if key.ID == id {
+			key.LastUsedAt = &at
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *MockAPIKeyRepository) Revoke(userID, id uint) error {
+	for _, key := range m.Keys {
+		if key.ID == id && key.UserID == userID && !key.DeletedAt.Valid {
+			key.DeletedAt.Time, key.DeletedAt.Valid = time.Now(), true
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
@@ -0,0 +1,30 @@
+package auth_test
+
+import (
+	"Auth/internal/model"
+	"context"
+	"errors"
+)
+
+type MockClientRepository struct {
+	CreateFunc func(client *model.Client) error
+	Clients    map[string]*model.Client
+}
+
+func (m *MockClientRepository) Create(client *model.Client) error {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(client)
+	}
+	if m.Clients == nil {
+		m.Clients = map[string]*model.Client{}
+	}
+	m.Clients[client.ClientID] = client
+	return nil
+}
+
+func (m *MockClientRepository) FindByClientID(ctx context.Context, clientID string) (*model.Client, error) {
+	if client, ok := m.Clients[clientID]; ok {
+		return client, nil
+	}
+	return nil, errors.New("not found")
+}
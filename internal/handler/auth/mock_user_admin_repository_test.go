@@ -0,0 +1,91 @@
+package auth_test
+
+import (
+	"Auth/internal/model"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// MockUserAdminRepository keeps users and roles in memory and remembers the
+// filter users were last listed with.
+type MockUserAdminRepository struct {
+	Users      []*model.User
+	Roles      []model.Role
+	LastFilter model.UserFilter
+}
+
+func (m *MockUserAdminRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
+	for _, user := range m.Users {
+		if strings.EqualFold(user.Username, username) {
+			return user, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *MockUserAdminRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	for _, user := range m.Users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *MockUserAdminRepository) List(ctx context.Context, filter model.UserFilter) ([]model.User, int64, error) {
+	m.LastFilter = filter
+
+	matching := []model.User{}
+	for _, user := range m.Users {
+		if filter.Role != "" && !slices.Contains(user.RoleNames(), filter.Role) {
+			continue
+		}
+		if filter.Status != "" && user.AccountStatus() != filter.Status {
+			continue
+		}
+		matching = append(matching, *user)
+	}
+
+	page := matching[min(filter.Offset, len(matching)):min(filter.Offset+filter.Limit, len(matching))]
+	return page, int64(len(matching)), nil
+}
+
+func (m *MockUserAdminRepository) FindRoles(ctx context.Context, names []string) ([]model.Role, error) {
+	roles := []model.Role{}
+	for _, role := range m.Roles {
+		if slices.Contains(names, role.Name) {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (m *MockUserAdminRepository) SetRoles(user *model.User, roles []model.Role) error {
+	user.Roles = roles
+	return nil
+}
+
+func (m *MockUserAdminRepository) Update(user *model.User) error {
+	return nil
+}
+
+func (m *MockUserAdminRepository) Delete(username string) error {
+	for i, user := range m.Users {
+		if strings.EqualFold(user.Username, username) {
+			m.Users = slices.Delete(m.Users, i, i+1)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (m *MockUserAdminRepository) IncrementTokenVersion(username string) error {
+	user, err := m.FindByUsername(context.Background(), username)
+	if err != nil {
+		return err
+	}
+	user.TokenVersion++
+	return nil
+}
@@ -7,10 +7,12 @@ import (
 )
 
 type MockUserRepository struct {
-	CreateFunc         func(user *model.User) error
-	FindByUsernameFunc func(ctx context.Context, username string) (*model.User, error)
-	DeleteFunc         func(username string) error
-	User               *model.User
+	CreateFunc                func(user *model.User) error
+	FindByUsernameFunc        func(ctx context.Context, username string) (*model.User, error)
+	DeleteFunc                func(username string) error
+	IncrementTokenVersionFunc func(username string) error
+	UpdateFunc                func(user *model.User) error
+	User                      *model.User
 }
 
 func (m *MockUserRepository) Create(user *model.User) error {
@@ -36,3 +38,32 @@ func (m *MockUserRepository) Delete(username string) error {
 	}
 	return nil
 }
+
+func (m *MockUserRepository) Update(user *model.User) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(user)
+	}
+	m.User = user
+	return nil
+}
+
+func (m *MockUserRepository) IncrementTokenVersion(username string) error {
+	if m.IncrementTokenVersionFunc != nil {
+		return m.IncrementTokenVersionFunc(username)
+	}
+	if m.User != nil && m.User.Username == username {
+		m.User.TokenVersion++
+		return nil
+	}
+	return errors.New("not found")
+}
+
+// newAnyUserRepository returns a repository that knows every username, for
+// tests that only care about the token itself.
+func newAnyUserRepository() *MockUserRepository {
+	return &MockUserRepository{
+		FindByUsernameFunc: func(ctx context.Context, username string) (*model.User, error) {
+			return &model.User{Username: username}, nil
+		},
+	}
+}
@@ -0,0 +1,43 @@
+package auth_test
+
+import (
+	"Auth/internal/notify"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type MockNotifier struct {
+	NotifyFunc func(ctx context.Context, msg notify.Message) error
+	Sent       []notify.Message
+	mu         sync.Mutex
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	if m.NotifyFunc != nil {
+		return m.NotifyFunc(ctx, msg)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Sent = append(m.Sent, msg)
+	return nil
+}
+
+// waitForSent returns the messages once n were sent, for handlers that
+// notify after answering the request.
+func (m *MockNotifier) waitForSent(t *testing.T, n int) []notify.Message {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.Sent) >= n
+	}, time.Second, 5*time.Millisecond)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.Sent)
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/identity"
+	"Auth/internal/model"
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorizeError is an error of an authorization request. Errors found once
+// the client and its redirect URI are known are sent back to the client,
+// anything before that is shown to the user.
+type authorizeError struct {
+	code        string
+	description string
+	redirect    bool
+}
+
+// OAuthAuthorizeHandler shows the login and consent page for an
+// authorization code request. Only the S256 PKCE method is supported and a
+// challenge is required from every client.
+func OAuthAuthorizeHandler(clients ClientFindRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input OAuthAuthorizeInput
+		_ = ctx.ShouldBindQuery(&input)
+
+		client, authErr := validateAuthorizeRequest(ctx.Request.Context(), clients, input)
+		if authErr != nil {
+			respondAuthorizeError(ctx, input, authErr)
+			return
+		}
+
+		renderAuthorizePage(ctx, http.StatusOK, authorizePageData{Client: client, Request: input})
+	}
+}
+
+// OAuthConsentHandler handles the submitted login page. Once the user is
+// authenticated and approved the request, it redirects back to the client
+// with a single-use authorization code.
+func OAuthConsentHandler(
+	clients ClientFindRepository,
+	users UserFindByUsernameRepository,
+	lockout config.LockoutConfig,
+	emailConfig config.EmailVerificationConfig,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input OAuthConsentInput
+		_ = ctx.ShouldBind(&input)
+
+		client, authErr := validateAuthorizeRequest(ctx.Request.Context(), clients, input.OAuthAuthorizeInput)
+		if authErr != nil {
+			respondAuthorizeError(ctx, input.OAuthAuthorizeInput, authErr)
+			return
+		}
+
+		if input.Decision != "allow" {
+			redirectAuthorizeError(ctx, input.OAuthAuthorizeInput, &authorizeError{
+				code:        "access_denied",
+				description: "the user denied the request",
+			})
+			return
+		}
+
+		page := authorizePageData{Client: client, Request: input.OAuthAuthorizeInput}
+
+		username := identity.NormalizeUsername(input.Username)
+		if remaining := loginLockedFor(cache, lockout, username, ctx.ClientIP()); remaining > 0 {
+			ctx.Header("Retry-After", retryAfter(remaining))
+			page.Error = "too many failed login attempts, try again later"
+			renderAuthorizePage(ctx, http.StatusTooManyRequests, page)
+			return
+		}
+
+		user, err := findUser(ctx.Request.Context(), users, cache, username)
+		if err != nil || !user.CheckPassword(input.Password) {
+			recordLoginFailure(cache, lockout, username, ctx.ClientIP())
+			page.Error = "invalid credentials"
+			renderAuthorizePage(ctx, http.StatusUnauthorized, page)
+			return
+		}
+		resetLoginFailures(cache, lockout, username)
+
+		if err := accountStatusError(user); err != nil {
+			page.Error = accountStatusPageErrors[err]
+			renderAuthorizePage(ctx, http.StatusForbidden, page)
+			return
+		}
+
+		if emailConfig.Required && !user.EmailVerified {
+			page.Error = "verify your email address before logging in"
+			renderAuthorizePage(ctx, http.StatusForbidden, page)
+			return
+		}
+
+		if user.TOTPEnabled {
+			// the cached user lacks the TOTP secret
+			reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+			defer cancel()
+
+			stored, err := users.FindByUsername(reqCtx, user.Username)
+			if err != nil || !verifyTOTPCode(cache, user.Username, stored.TOTPSecret, input.TOTPCode) {
+				page.Error = "invalid authenticator code"
+				renderAuthorizePage(ctx, http.StatusUnauthorized, page)
+				return
+			}
+		}
+
+		code, err := newAuthorizationCode(cache, client, input.OAuthAuthorizeInput, user)
+		if err != nil {
+			log.Printf("could not issue authorization code for %s: %v", user.Username, err)
+			redirectAuthorizeError(ctx, input.OAuthAuthorizeInput, &authorizeError{
+				code:        "server_error",
+				description: "could not issue authorization code",
+			})
+			return
+		}
+
+		redirectToClient(ctx, input.RedirectURI, url.Values{"code": {code}}, input.State)
+	}
+}
+
+var accountStatusPageErrors = map[error]string{
+	errAccountDisabled:            "your account has been disabled",
+	errAccountLocked:              "your account has been locked",
+	errAccountPendingVerification: "verify your email address before logging in",
+}
+
+func validateAuthorizeRequest(ctx context.Context, clients ClientFindRepository, input OAuthAuthorizeInput) (*model.Client, *authorizeError) {
+	if input.ClientID == "" {
+		return nil, &authorizeError{code: "invalid_request", description: "client_id is required"}
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	client, err := clients.FindByClientID(reqCtx, input.ClientID)
+	if err != nil {
+		return nil, &authorizeError{code: "invalid_client", description: "unknown client"}
+	}
+
+	if input.RedirectURI == "" || !client.HasRedirectURI(input.RedirectURI) {
+		return nil, &authorizeError{code: "invalid_request", description: "redirect_uri is not registered for the client"}
+	}
+
+	if !client.AllowsGrant(model.GrantAuthorizationCode) {
+		return nil, &authorizeError{code: "unauthorized_client", description: "the client may not use the authorization code flow", redirect: true}
+	}
+
+	if input.ResponseType != "code" {
+		return nil, &authorizeError{code: "unsupported_response_type", description: "only the code response type is supported", redirect: true}
+	}
+
+	if input.CodeChallengeMethod != "S256" || len(input.CodeChallenge) != 43 || !pkceValue.MatchString(input.CodeChallenge) {
+		return nil, &authorizeError{code: "invalid_request", description: "a S256 code_challenge is required", redirect: true}
+	}
+
+	return client, nil
+}
+
+func newAuthorizationCode(cache Cache, client *model.Client, input OAuthAuthorizeInput, user *model.User) (string, error) {
+	family, err := newRefreshFamily()
+	if err != nil {
+		return "", err
+	}
+
+	return issueAuthorizationCode(cache, authorizationCode{
+		ClientID:      client.ClientID,
+		RedirectURI:   input.RedirectURI,
+		CodeChallenge: input.CodeChallenge,
+		Username:      user.Username,
+		UserID:        user.ID,
+		TokenVersion:  user.TokenVersion,
+		Family:        family,
+		Scopes:        grantedUserScopes(input.Scope),
+		Nonce:         input.Nonce,
+		AuthTime:      time.Now().Unix(),
+	})
+}
+
+func respondAuthorizeError(ctx *gin.Context, input OAuthAuthorizeInput, authErr *authorizeError) {
+	if authErr.redirect {
+		redirectAuthorizeError(ctx, input, authErr)
+		return
+	}
+	renderAuthorizePage(ctx, http.StatusBadRequest, authorizePageData{Error: authErr.description})
+}
+
+func redirectAuthorizeError(ctx *gin.Context, input OAuthAuthorizeInput, authErr *authorizeError) {
+	redirectToClient(ctx, input.RedirectURI, url.Values{
+		"error":             {authErr.code},
+		"error_description": {authErr.description},
+	}, input.State)
+}
+
+// redirectToClient sends the user agent back to the already validated
+// redirect URI, keeping any query the client registered it with.
+func redirectToClient(ctx *gin.Context, redirectURI string, params url.Values, state string) {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		renderAuthorizePage(ctx, http.StatusBadRequest, authorizePageData{Error: "invalid redirect_uri"})
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	target.RawQuery = query.Encode()
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Redirect(http.StatusFound, target.String())
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type registeredClient struct {
+	*model.Client
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// OAuthClientRegisterHandler registers an application with the OAuth 2.0
+// endpoints. Clients using only the authorization code flow are public and
+// prove the code is theirs with PKCE. Clients allowed the client credentials
+// grant get a secret, which is returned once and only stored hashed.
+func OAuthClientRegisterHandler(repo ClientCreateRepository) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input OAuthClientInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Name == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+
+		if len(input.GrantTypes) == 0 {
+			input.GrantTypes = []string{model.GrantAuthorizationCode}
+		}
+		for _, grantType := range input.GrantTypes {
+			if grantType != model.GrantAuthorizationCode && grantType != model.GrantClientCredentials {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "unsupported grant type: " + grantType})
+				return
+			}
+		}
+
+		if slices.Contains(input.GrantTypes, model.GrantAuthorizationCode) && len(input.RedirectURIs) == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "redirect_uris are required for the authorization code grant"})
+			return
+		}
+		for _, redirectURI := range input.RedirectURIs {
+			if !validRedirectURI(redirectURI) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid redirect uri: " + redirectURI})
+				return
+			}
+		}
+
+		for _, scope := range input.Scopes {
+			if scope == "" || strings.ContainsAny(scope, " \\\"") {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid scope: " + scope})
+				return
+			}
+		}
+
+		clientID, err := newTokenID()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not register client"})
+			return
+		}
+
+		client := &model.Client{
+			ClientID:     clientID,
+			Name:         input.Name,
+			RedirectURIs: input.RedirectURIs,
+			GrantTypes:   input.GrantTypes,
+			Scopes:       input.Scopes,
+		}
+
+		var secret string
+		if slices.Contains(input.GrantTypes, model.GrantClientCredentials) {
+			if secret, err = newOpaqueToken(); err == nil {
+				err = client.SetSecret(secret)
+			}
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not register client"})
+				return
+			}
+		}
+
+		if err := repo.Create(client); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not register client"})
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, registeredClient{Client: client, ClientSecret: secret})
+	}
+}
+
+// validRedirectURI accepts absolute URIs without a fragment, RFC 6749
+// section 3.1.2. Custom schemes are allowed for native apps, schemes that run
+// code in the browser are not.
+func validRedirectURI(redirectURI string) bool {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || !parsed.IsAbs() || parsed.Fragment != "" || parsed.Opaque != "" {
+		return false
+	}
+
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return parsed.Host != ""
+	case "javascript", "data", "vbscript":
+		return false
+	}
+	return true
+}
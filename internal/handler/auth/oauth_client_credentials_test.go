@@ -0,0 +1,177 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testServiceClientID = "billing-job"
+	testServiceSecret   = "service-secret"
+)
+
+func newClientCredentialsRouter(t *testing.T) (*gin.Engine, *MockClientRepository) {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: policyAdmin()}
+
+	service := &model.Client{
+		ClientID:   testServiceClientID,
+		Name:       "Billing job",
+		GrantTypes: []string{model.GrantClientCredentials},
+		Scopes:     []string{"invoices:read", "invoices:write"},
+	}
+	require.NoError(t, service.SetSecret(testServiceSecret))
+
+	clients := &MockClientRepository{Clients: map[string]*model.Client{
+		testServiceClientID: service,
+		testClientID:        {ClientID: testClientID, Name: "Single Page App", RedirectURIs: []string{testRedirectURI}},
+	}}
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, clients, sessionCfg)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/logout/all", authenticated, auth.LogoutAllHandler(repo, cache))
+	r.POST("/admin/oauth/clients", authenticated, auth.RequireRole("admin"), auth.OAuthClientRegisterHandler(clients))
+	r.POST("/oauth/token", auth.OAuthTokenHandler(clients, repo, refreshTokenCfg, oidcCfg, keys, cache))
+	r.GET("/auth", authenticated, auth.AuthorizeHandler(), auth.ForwardAuthHandler(defaultForwardAuthCfg))
+	return r, clients
+}
+
+func clientCredentials(r *gin.Engine, clientID, secret string, form url.Values) (*httptest.ResponseRecorder, map[string]any) {
+	form.Set("grant_type", "client_credentials")
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	var body map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp, body
+}
+
+func TestClientCredentials_IssuesScopedToken(t *testing.T) {
+	r, _ := newClientCredentialsRouter(t)
+
+	resp, body := clientCredentials(r, testServiceClientID, testServiceSecret, url.Values{"scope": {"invoices:read"}})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "invoices:read", body["scope"])
+	assert.Nil(t, body["refresh_token"])
+
+	token := body["access_token"].(string)
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	assert.Equal(t, testServiceClientID, claims["sub"])
+	assert.Nil(t, claims["username"])
+
+	authResp := getWithToken(r, "/auth?permission=invoices:read", token)
+	require.Equal(t, http.StatusOK, authResp.Code, authResp.Body.String())
+	assert.Equal(t, testServiceClientID, authResp.Header().Get("X-Auth-Client-Id"))
+	assert.Empty(t, authResp.Header().Get("X-Auth-User"))
+
+	authResp = getWithToken(r, "/auth?permission=invoices:write", token)
+	assert.Equal(t, http.StatusForbidden, authResp.Code)
+
+	authResp = getWithToken(r, "/auth?role=admin", token)
+	assert.Equal(t, http.StatusForbidden, authResp.Code)
+}
+
+func TestClientCredentials_DefaultsToAllScopes(t *testing.T) {
+	r, _ := newClientCredentialsRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {testServiceClientID},
+		"client_secret": {testServiceSecret},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"scope":"invoices:read invoices:write"`)
+}
+
+func TestClientCredentials_Rejections(t *testing.T) {
+	r, _ := newClientCredentialsRouter(t)
+
+	resp, body := clientCredentials(r, testServiceClientID, "wrong", url.Values{})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "invalid_client", body["error"])
+	assert.NotEmpty(t, resp.Header().Get("WWW-Authenticate"))
+
+	resp, body = clientCredentials(r, testServiceClientID, testServiceSecret, url.Values{"scope": {"users:delete"}})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid_scope", body["error"])
+
+	resp, body = clientCredentials(r, testClientID, "", url.Values{})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "unauthorized_client", body["error"])
+}
+
+func TestClientCredentials_TokenCannotActAsUser(t *testing.T) {
+	r, _ := newClientCredentialsRouter(t)
+
+	_, body := clientCredentials(r, testServiceClientID, testServiceSecret, url.Values{})
+	resp := postAuthenticated(r, "/logout/all", body["access_token"].(string), nil)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestClientCredentials_TokenEndsWithTheClient(t *testing.T) {
+	r, clients := newClientCredentialsRouter(t)
+
+	_, body := clientCredentials(r, testServiceClientID, testServiceSecret, url.Values{})
+	token := body["access_token"].(string)
+	require.Equal(t, http.StatusOK, getWithToken(r, "/auth", token).Code)
+
+	clients.Clients[testServiceClientID].Scopes = []string{"invoices:read"}
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(r, "/auth", token).Code)
+
+	_, body = clientCredentials(r, testServiceClientID, testServiceSecret, url.Values{})
+	token = body["access_token"].(string)
+	require.Equal(t, http.StatusOK, getWithToken(r, "/auth", token).Code)
+
+	delete(clients.Clients, testServiceClientID)
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(r, "/auth", token).Code)
+}
+
+func TestOAuthClientRegisterHandler_ConfidentialClient(t *testing.T) {
+	r, clients := newClientCredentialsRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := postAuthenticated(r, "/admin/oauth/clients", token, map[string]any{
+		"name":        "Reports",
+		"grant_types": []string{"client_credentials"},
+		"scopes":      []string{"reports:read"},
+	})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var registered struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &registered))
+	require.NotEmpty(t, registered.ClientSecret)
+	assert.NotContains(t, resp.Body.String(), clients.Clients[registered.ClientID].SecretHash)
+
+	tokenResp, body := clientCredentials(r, registered.ClientID, registered.ClientSecret, url.Values{})
+	require.Equal(t, http.StatusOK, tokenResp.Code, tokenResp.Body.String())
+	assert.Equal(t, "reports:read", body["scope"])
+
+	resp = postAuthenticated(r, "/admin/oauth/clients", token, map[string]any{"name": "Bad", "grant_types": []string{"password"}})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
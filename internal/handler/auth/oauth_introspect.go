@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OAuthIntrospectHandler tells a confidential client whether a token is
+// active and whom it was issued to, RFC 7662. Access tokens go through the
+// same checks as in AuthHandler. Anything that is not an active token gets
+// only {"active": false}.
+func OAuthIntrospectHandler(
+	clients ClientFindRepository,
+	users UserFindByUsernameRepository,
+	keys *signing.KeyRing,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "no-store")
+
+		var input OAuthTokenHintInput
+		_ = ctx.ShouldBind(&input)
+
+		client, ok := requireClientAuthentication(ctx, clients, input.ClientID, input.ClientSecret)
+		if !ok {
+			return
+		}
+		if !client.Confidential() {
+			oauthError(ctx, http.StatusUnauthorized, "invalid_client", "introspection requires a confidential client")
+			return
+		}
+
+		if input.Token == "" {
+			oauthError(ctx, http.StatusBadRequest, "invalid_request", "token is required")
+			return
+		}
+
+		introspectors := []func() gin.H{
+			func() gin.H { return introspectAccessToken(ctx, keys, cache, users, clients, input.Token) },
+			func() gin.H { return introspectRefreshToken(ctx, cache, users, input.Token) },
+		}
+		if input.TokenTypeHint == "refresh_token" {
+			introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+		}
+
+		for _, introspect := range introspectors {
+			if response := introspect(); response != nil {
+				ctx.JSON(http.StatusOK, response)
+				return
+			}
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"active": false})
+	}
+}
+
+// OAuthRevokeHandler revokes an access or refresh token, RFC 7009. Access
+// tokens are put on the blacklist AuthHandler consults, refresh tokens have
+// their family revoked. Clients can only revoke tokens issued to them, tokens
+// from /login belong to no client and are ended with /logout.
+//
+// Unknown tokens are answered with 200 as well, there is nothing to revoke.
+func OAuthRevokeHandler(
+	clients ClientFindRepository,
+	tokenConfig config.JWTConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input OAuthTokenHintInput
+		_ = ctx.ShouldBind(&input)
+
+		client, ok := requireClientAuthentication(ctx, clients, input.ClientID, input.ClientSecret)
+		if !ok {
+			return
+		}
+
+		if input.Token == "" {
+			oauthError(ctx, http.StatusBadRequest, "invalid_request", "token is required")
+			return
+		}
+
+		if claims, ok := parseAccessToken(keys, input.Token); ok {
+			if !mayRevoke(client, stringClaim(claims, "client_id")) {
+				oauthError(ctx, http.StatusBadRequest, "unauthorized_client", "token was issued to another client")
+				return
+			}
+
+			var expiresAt time.Time
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+				expiresAt = exp.Time
+			}
+			if err := blacklistToken(cache, tokenIDFromClaims(claims, input.Token), expiresAt); err != nil {
+				oauthError(ctx, http.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token")
+				return
+			}
+
+			ctx.Status(http.StatusOK)
+			return
+		}
+
+		if record, ok := activeRefreshToken(cache, input.Token); ok {
+			if !mayRevoke(client, record.ClientID) {
+				oauthError(ctx, http.StatusBadRequest, "unauthorized_client", "token was issued to another client")
+				return
+			}
+
+			if err := revokeRefreshFamily(cache, record.Family, refreshTokenTTL(tokenConfig)); err != nil {
+				oauthError(ctx, http.StatusServiceUnavailable, "temporarily_unavailable", "could not revoke token")
+				return
+			}
+		}
+
+		ctx.Status(http.StatusOK)
+	}
+}
+
+func introspectAccessToken(
+	ctx *gin.Context,
+	keys *signing.KeyRing,
+	cache Cache,
+	users UserFindByUsernameRepository,
+	clients ClientFindRepository,
+	tokenString string,
+) gin.H {
+	token, err := verifyAccessToken(ctx.Request.Context(), keys, cache, users, clients, tokenString)
+	if err != nil {
+		return nil
+	}
+
+	response := gin.H{"active": true, "token_type": "Bearer", "jti": token.ID}
+	if token.User != nil {
+		response["sub"] = subject(token.User)
+		response["username"] = token.User.Username
+	} else {
+		response["sub"] = token.ClientID
+	}
+	if token.ClientID != "" {
+		response["client_id"] = token.ClientID
+	}
+	if len(token.Scopes) > 0 {
+		response["scope"] = strings.Join(token.Scopes, " ")
+	}
+	if exp, err := token.Claims.GetExpirationTime(); err == nil && exp != nil {
+		response["exp"] = exp.Unix()
+	}
+	if iat, err := token.Claims.GetIssuedAt(); err == nil && iat != nil {
+		response["iat"] = iat.Unix()
+	}
+
+	return response
+}
+
+func introspectRefreshToken(ctx *gin.Context, cache Cache, users UserFindByUsernameRepository, tokenString string) gin.H {
+	record, ok := activeRefreshToken(cache, tokenString)
+	if !ok {
+		return nil
+	}
+
+	user, err := findUser(ctx.Request.Context(), users, cache, record.Username)
+	if err != nil || !sameAccount(user, record.UserID, record.TokenVersion) || !user.Active() {
+		return nil
+	}
+
+	response := gin.H{
+		"active":     true,
+		"token_type": "refresh_token",
+		"sub":        subject(user),
+		"username":   user.Username,
+	}
+	if record.ClientID != "" {
+		response["client_id"] = record.ClientID
+	}
+	if len(record.Scopes) > 0 {
+		response["scope"] = strings.Join(record.Scopes, " ")
+	}
+	if record.ExpiresAt != 0 {
+		response["exp"] = record.ExpiresAt
+	}
+
+	return response
+}
+
+// parseAccessToken checks only that the token is a well formed access token
+// signed by us. Expired or already revoked tokens still parse, revoking them
+// again does no harm.
+func parseAccessToken(keys *signing.KeyRing, tokenString string) (jwt.MapClaims, bool) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.Keyfunc, jwt.WithoutClaimsValidation())
+	if err != nil || !token.Valid {
+		return nil, false
+	}
+	if _, exists := claims["purpose"]; exists {
+		return nil, false
+	}
+	return claims, true
+}
+
+// mayRevoke lets a client revoke only the tokens issued to it, RFC 7009
+// section 2.1. Tokens from /login belong to no client and are ended with
+// /logout instead.
+func mayRevoke(client *model.Client, issuedTo string) bool {
+	return issuedTo != "" && issuedTo == client.ClientID
+}
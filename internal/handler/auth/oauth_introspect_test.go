@@ -0,0 +1,185 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIntrospectionRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: editor()}
+
+	service := &model.Client{
+		ClientID:   testServiceClientID,
+		Name:       "Billing job",
+		GrantTypes: []string{model.GrantClientCredentials},
+		Scopes:     []string{"invoices:read"},
+	}
+	require.NoError(t, service.SetSecret(testServiceSecret))
+
+	clients := &MockClientRepository{Clients: map[string]*model.Client{
+		testServiceClientID: service,
+		testClientID:        {ClientID: testClientID, Name: "Single Page App", RedirectURIs: []string{testRedirectURI}},
+		"other-spa":         {ClientID: "other-spa", Name: "Other App", RedirectURIs: []string{testRedirectURI}},
+	}}
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/oauth/authorize", auth.OAuthConsentHandler(clients, repo, lockoutCfg, emailCfg, cache))
+	r.POST("/oauth/token", auth.OAuthTokenHandler(clients, repo, refreshTokenCfg, oidcCfg, keys, cache))
+	r.POST("/introspect", auth.OAuthIntrospectHandler(clients, repo, keys, cache))
+	r.POST("/revoke", auth.OAuthRevokeHandler(clients, refreshTokenCfg, keys, cache))
+	r.GET("/auth", auth.AuthHandler(keys, cache, repo, nil, clients, sessionCfg))
+	return r
+}
+
+func postAsClient(r *gin.Engine, path, clientID, secret string, form url.Values) (*httptest.ResponseRecorder, map[string]any) {
+	if secret == "" && clientID != "" {
+		form.Set("client_id", clientID)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if secret != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	var body map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp, body
+}
+
+func introspect(r *gin.Engine, token string) map[string]any {
+	_, body := postAsClient(r, "/introspect", testServiceClientID, testServiceSecret, url.Values{"token": {token}})
+	return body
+}
+
+func TestIntrospect_AccessTokenFromAuthorizationCode(t *testing.T) {
+	r := newIntrospectionRouter(t)
+
+	params := loginParams("alice", "secret")
+	params.Set("scope", "profile")
+	_, tokens := exchangeCode(r, approve(t, r, params).Get("code"), testVerifier)
+
+	body := introspect(r, tokens["access_token"].(string))
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, "alice", body["username"])
+	assert.Equal(t, testClientID, body["client_id"])
+	assert.Equal(t, "profile", body["scope"])
+	assert.NotEmpty(t, body["exp"])
+
+	body = introspect(r, tokens["refresh_token"].(string))
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, "refresh_token", body["token_type"])
+	assert.Equal(t, testClientID, body["client_id"])
+}
+
+func TestIntrospect_ClientToken(t *testing.T) {
+	r := newIntrospectionRouter(t)
+
+	_, tokens := clientCredentials(r, testServiceClientID, testServiceSecret, url.Values{})
+
+	body := introspect(r, tokens["access_token"].(string))
+	assert.Equal(t, true, body["active"])
+	assert.Equal(t, testServiceClientID, body["sub"])
+	assert.Equal(t, "invoices:read", body["scope"])
+	assert.Nil(t, body["username"])
+}
+
+func TestIntrospect_InactiveTokens(t *testing.T) {
+	r := newIntrospectionRouter(t)
+
+	assert.Equal(t, map[string]any{"active": false}, introspect(r, "garbage"))
+
+	_, tokens := exchangeCode(r, approve(t, r, loginParams("alice", "secret")).Get("code"), testVerifier)
+	token := tokens["access_token"].(string)
+	resp, _ := postAsClient(r, "/revoke", testClientID, "", url.Values{"token": {token}})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, map[string]any{"active": false}, introspect(r, token))
+}
+
+func TestIntrospect_RequiresConfidentialClient(t *testing.T) {
+	r := newIntrospectionRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp, body := postAsClient(r, "/introspect", "", "", url.Values{"token": {token}})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, _ = postAsClient(r, "/introspect", testClientID, "", url.Values{"token": {token}})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp, _ = postAsClient(r, "/introspect", testServiceClientID, "wrong", url.Values{"token": {token}})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestRevoke_AccessTokenIsBlacklisted(t *testing.T) {
+	r := newIntrospectionRouter(t)
+	_, tokens := exchangeCode(r, approve(t, r, loginParams("alice", "secret")).Get("code"), testVerifier)
+	accessToken := tokens["access_token"].(string)
+	require.Equal(t, http.StatusOK, getAuth(r, accessToken).Code)
+
+	resp, body := postAsClient(r, "/revoke", "other-spa", "", url.Values{"token": {accessToken}})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "unauthorized_client", body["error"])
+	assert.Equal(t, http.StatusOK, getAuth(r, accessToken).Code)
+
+	resp, _ = postAsClient(r, "/revoke", testClientID, "", url.Values{"token": {accessToken}})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, accessToken).Code)
+}
+
+func TestRevoke_RefreshTokenRevokesFamily(t *testing.T) {
+	r := newIntrospectionRouter(t)
+	_, tokens := exchangeCode(r, approve(t, r, loginParams("alice", "secret")).Get("code"), testVerifier)
+	refreshToken := tokens["refresh_token"].(string)
+
+	resp, _ := postAsClient(r, "/revoke", testClientID, "", url.Values{"token": {refreshToken}, "token_type_hint": {"refresh_token"}})
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	refreshResp := postForm(r, "/oauth/token", url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}, "client_id": {testClientID}})
+	assert.Equal(t, http.StatusBadRequest, refreshResp.Code)
+	assert.Equal(t, false, introspect(r, refreshToken)["active"])
+}
+
+func TestRevoke_ConfidentialClientCannotRevokeOtherTokens(t *testing.T) {
+	r := newIntrospectionRouter(t)
+	_, tokens := exchangeCode(r, approve(t, r, loginParams("alice", "secret")).Get("code"), testVerifier)
+	loginTokens := login(t, r, "alice", "secret")
+
+	for _, token := range []string{
+		tokens["access_token"].(string),
+		tokens["refresh_token"].(string),
+		loginTokens["token"].(string),
+		loginTokens["refresh_token"].(string),
+	} {
+		resp, body := postAsClient(r, "/revoke", testServiceClientID, testServiceSecret, url.Values{"token": {token}})
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+		assert.Equal(t, "unauthorized_client", body["error"])
+	}
+
+	assert.Equal(t, http.StatusOK, getAuth(r, tokens["access_token"].(string)).Code)
+	assert.Equal(t, http.StatusOK, getAuth(r, loginTokens["token"].(string)).Code)
+	assert.Equal(t, true, introspect(r, tokens["refresh_token"].(string))["active"])
+}
+
+func TestRevoke_UnknownTokenIsAccepted(t *testing.T) {
+	r := newIntrospectionRouter(t)
+
+	resp, _ := postAsClient(r, "/revoke", testClientID, "", url.Values{"token": {"unknown"}})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"html/template"
+
+	"github.com/gin-gonic/gin"
+)
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in</title>
+</head>
+<body>
+{{if .Client}}
+<h1>Sign in to {{.Client.Name}}</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+<p><label>Username <input name="username" autocomplete="username" required></label></p>
+<p><label>Password <input type="password" name="password" autocomplete="current-password" required></label></p>
+<p><label>Authenticator code, if enabled <input name="totp_code" inputmode="numeric" autocomplete="one-time-code"></label></p>
+<p>{{.Client.Name}} will be able to act on your behalf.</p>
+<button type="submit" name="decision" value="allow">Allow</button>
+<button type="submit" name="decision" value="deny" formnovalidate>Deny</button>
+</form>
+{{else}}
+<h1>Authorization failed</h1>
+<p role="alert">{{.Error}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+type authorizePageData struct {
+	Client  *model.Client
+	Request OAuthAuthorizeInput
+	Error   string
+}
+
+// renderAuthorizePage renders the login and consent page. The page must not
+// be framed by other sites, that would let them trick users into approving.
+func renderAuthorizePage(ctx *gin.Context, status int, data authorizePageData) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("X-Frame-Options", "DENY")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Status(status)
+	_ = authorizePage.Execute(ctx.Writer, data)
+}
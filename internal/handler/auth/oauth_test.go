@@ -0,0 +1,358 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID    = "spa"
+	testRedirectURI = "https://app.example.com/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testWebClientID = "web"
+	testWebSecret   = "web-secret"
+)
+
+func newOAuthRouter(t *testing.T, repo *MockUserRepository, cache *MockCacheRepository) *gin.Engine {
+	return newOAuthRouterWithKeys(t, repo, cache, newTestKeyRing(t, testSecret))
+}
+
+func newOAuthRouterWithKeys(t *testing.T, repo *MockUserRepository, cache *MockCacheRepository, keys *signing.KeyRing) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	web := &model.Client{ClientID: testWebClientID, Name: "Web App", RedirectURIs: []string{testRedirectURI}}
+	require.NoError(t, web.SetSecret(testWebSecret))
+	clients := &MockClientRepository{Clients: map[string]*model.Client{
+		testClientID:    {ClientID: testClientID, Name: "Single Page App", RedirectURIs: []string{testRedirectURI}},
+		testWebClientID: web,
+	}}
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/token/refresh", auth.RefreshTokenHandler(repo, refreshTokenCfg, keys, cache))
+	r.POST("/admin/oauth/clients", authenticated, auth.RequireRole("admin"), auth.OAuthClientRegisterHandler(clients))
+	r.GET("/oauth/authorize", auth.OAuthAuthorizeHandler(clients))
+	r.POST("/oauth/authorize", auth.OAuthConsentHandler(clients, repo, lockoutCfg, emailCfg, cache))
+	r.POST("/oauth/token", auth.OAuthTokenHandler(clients, repo, refreshTokenCfg, oidcCfg, keys, cache))
+	r.GET("/auth", authenticated)
+	return r
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeParams() url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURI},
+		"state":                 {"xyz"},
+		"code_challenge":        {codeChallenge(testVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func postForm(r *gin.Engine, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+// approve submits the login page and returns the query of the redirect.
+func approve(t *testing.T, r *gin.Engine, params url.Values) url.Values {
+	t.Helper()
+	form := url.Values{}
+	for key, values := range params {
+		form[key] = values
+	}
+	form.Set("decision", "allow")
+
+	resp := postForm(r, "/oauth/authorize", form)
+	require.Equal(t, http.StatusFound, resp.Code, resp.Body.String())
+
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example.com", location.Host)
+	return location.Query()
+}
+
+func loginParams(username, password string) url.Values {
+	params := authorizeParams()
+	params.Set("username", username)
+	params.Set("password", password)
+	return params
+}
+
+func exchangeCode(r *gin.Engine, code, verifier string) (*httptest.ResponseRecorder, map[string]any) {
+	resp := postForm(r, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {testClientID},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {verifier},
+	})
+	var body map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp, body
+}
+
+func TestOAuth_AuthorizationCodeFlow(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+authorizeParams().Encode(), nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "Sign in to Single Page App")
+	assert.Equal(t, "DENY", resp.Header().Get("X-Frame-Options"))
+
+	query := approve(t, r, loginParams("alice", "secret"))
+	assert.Equal(t, "xyz", query.Get("state"))
+	require.NotEmpty(t, query.Get("code"))
+
+	tokenResp, body := exchangeCode(r, query.Get("code"), testVerifier)
+	require.Equal(t, http.StatusOK, tokenResp.Code, tokenResp.Body.String())
+	assert.Equal(t, "no-store", tokenResp.Header().Get("Cache-Control"))
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.NotEmpty(t, body["refresh_token"])
+
+	assert.Equal(t, http.StatusOK, getAuth(r, body["access_token"].(string)).Code)
+
+	refreshResp := postForm(r, "/oauth/token", url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {body["refresh_token"].(string)},
+		"client_id":     {testClientID},
+	})
+	assert.Equal(t, http.StatusOK, refreshResp.Code, refreshResp.Body.String())
+	assert.Contains(t, refreshResp.Body.String(), "access_token")
+}
+
+func TestOAuth_WrongVerifierIsRejected(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	query := approve(t, r, loginParams("alice", "secret"))
+
+	resp, body := exchangeCode(r, query.Get("code"), strings.Repeat("a", 43))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestOAuth_CodeReplayRevokesIssuedTokens(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	code := approve(t, r, loginParams("alice", "secret")).Get("code")
+
+	resp, body := exchangeCode(r, code, testVerifier)
+	require.Equal(t, http.StatusOK, resp.Code)
+	refreshToken := body["refresh_token"].(string)
+
+	resp, body = exchangeCode(r, code, testVerifier)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	refreshResp := postForm(r, "/oauth/token", url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}, "client_id": {testClientID}})
+	assert.Equal(t, http.StatusBadRequest, refreshResp.Code)
+}
+
+func refreshAt(r *gin.Engine, refreshToken string, client ...string) (*httptest.ResponseRecorder, map[string]any) {
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {refreshToken}}
+	if len(client) > 0 {
+		form.Set("client_id", client[0])
+	}
+	if len(client) > 1 {
+		form.Set("client_secret", client[1])
+	}
+	resp := postForm(r, "/oauth/token", form)
+	var body map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &body)
+	return resp, body
+}
+
+func TestOAuth_RefreshRequiresTheIssuingClient(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	_, tokens := exchangeCode(r, approve(t, r, loginParams("alice", "secret")).Get("code"), testVerifier)
+	refreshToken := tokens["refresh_token"].(string)
+
+	resp, body := refreshAt(r, refreshToken)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = refreshAt(r, refreshToken, testWebClientID, testWebSecret)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	// the refused attempts did not use the token up
+	resp, _ = refreshAt(r, refreshToken, testClientID)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestOAuth_ConfidentialClientRefreshNeedsItsSecret(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	params := loginParams("alice", "secret")
+	params.Set("client_id", testWebClientID)
+	resp := postForm(r, "/oauth/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {approve(t, r, params).Get("code")},
+		"client_id":     {testWebClientID},
+		"client_secret": {testWebSecret},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testVerifier},
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var tokens map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &tokens))
+	refreshToken := tokens["refresh_token"].(string)
+
+	resp, body := refreshAt(r, refreshToken, testWebClientID)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, body = refreshAt(r, refreshToken, testWebClientID, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	resp, _ = refreshAt(r, refreshToken, testWebClientID, testWebSecret)
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestOAuth_RefreshTokensStayAtTheirEndpoint(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	_, tokens := exchangeCode(r, approve(t, r, loginParams("alice", "secret")).Get("code"), testVerifier)
+	clientToken := tokens["refresh_token"].(string)
+	assert.Equal(t, http.StatusUnauthorized, refresh(r, clientToken).Code)
+
+	loginToken := login(t, r, "alice", "secret")["refresh_token"].(string)
+	resp, body := refreshAt(r, loginToken, testClientID)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, "invalid_grant", body["error"])
+
+	assert.Equal(t, http.StatusOK, refresh(r, loginToken).Code)
+	resp, _ = refreshAt(r, clientToken, testClientID)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestOAuth_AuthorizeRequiresPKCE(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	params := authorizeParams()
+	params.Set("code_challenge_method", "plain")
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusFound, resp.Code)
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+}
+
+func TestOAuth_UnregisteredRedirectIsNotFollowed(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	params := authorizeParams()
+	params.Set("redirect_uri", "https://evil.example.com/callback")
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+params.Encode(), nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Empty(t, resp.Header().Get("Location"))
+}
+
+func TestOAuth_InvalidCredentialsRenderPageAgain(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	form := loginParams("alice", "wrong")
+	form.Set("decision", "allow")
+	resp := postForm(r, "/oauth/authorize", form)
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid credentials")
+	assert.Empty(t, resp.Header().Get("Location"))
+}
+
+func TestOAuth_DenyRedirectsWithAccessDenied(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: editor()}, cache)
+
+	form := authorizeParams()
+	form.Set("decision", "deny")
+	resp := postForm(r, "/oauth/authorize", form)
+
+	require.Equal(t, http.StatusFound, resp.Code)
+	location, err := url.Parse(resp.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "access_denied", location.Query().Get("error"))
+}
+
+func TestOAuth_TOTPRequiredWhenEnabled(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	user := editor()
+	user.TOTPEnabled = true
+	user.TOTPSecret = "JBSWY3DPEHPK3PXP"
+	r := newOAuthRouter(t, &MockUserRepository{User: user}, cache)
+
+	form := loginParams("alice", "secret")
+	form.Set("decision", "allow")
+	resp := postForm(r, "/oauth/authorize", form)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid authenticator code")
+
+	params := loginParams("alice", "secret")
+	params.Set("totp_code", currentCode(t, user.TOTPSecret))
+	assert.NotEmpty(t, approve(t, r, params).Get("code"))
+}
+
+func TestOAuthClientRegisterHandler(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newOAuthRouter(t, &MockUserRepository{User: policyAdmin()}, cache)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := postAuthenticated(r, "/admin/oauth/clients", token, map[string]any{
+		"name":          "Mobile",
+		"redirect_uris": []string{"com.example.app:/oauth/callback", "https://mobile.example.com/cb"},
+	})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+
+	var client model.Client
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &client))
+	assert.NotEmpty(t, client.ClientID)
+	assert.Equal(t, "Mobile", client.Name)
+
+	for _, redirectURI := range []string{"/relative", "https://app.example.com/cb#fragment", "javascript:alert(1)"} {
+		resp = postAuthenticated(r, "/admin/oauth/clients", token, map[string]any{"name": "Bad", "redirect_uris": []string{redirectURI}})
+		assert.Equal(t, http.StatusBadRequest, resp.Code, redirectURI)
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errClientAuthentication = errors.New("client authentication failed")
+
+// OAuthTokenHandler is the token endpoint of the authorization server. It
+// exchanges authorization codes, rotates refresh tokens and issues tokens to
+// confidential clients with the client credentials grant. Tokens issued for
+// users are the same AuthHandler accepts from /login.
+func OAuthTokenHandler(
+	clients ClientFindRepository,
+	users UserFindByUsernameRepository,
+	tokenConfig config.JWTConfig,
+	oidcConfig config.OIDCConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "no-store")
+
+		var input OAuthTokenInput
+		if err := ctx.ShouldBind(&input); err != nil {
+			oauthError(ctx, http.StatusBadRequest, "invalid_request", "corrupted input payload")
+			return
+		}
+
+		switch input.GrantType {
+		case model.GrantAuthorizationCode:
+			client, ok := requireClient(ctx, clients, &input)
+			if !ok {
+				return
+			}
+			exchangeAuthorizationCode(ctx, client, users, tokenConfig, oidcConfig, keys, cache, input)
+		case model.GrantClientCredentials:
+			client, ok := requireClient(ctx, clients, &input)
+			if !ok {
+				return
+			}
+			issueClientCredentials(ctx, client, tokenConfig, keys, input)
+		case "refresh_token":
+			client, ok := requireClientAuthentication(ctx, clients, input.ClientID, input.ClientSecret)
+			if !ok {
+				return
+			}
+			pair, err := rotateRefreshToken(ctx.Request.Context(), users, tokenConfig, keys, cache, input.RefreshToken, client.ClientID)
+			if errors.Is(err, errRefreshTokenReused) || errors.Is(err, errRefreshTokenInvalid) || errors.Is(err, errRefreshTokenClient) {
+				oauthError(ctx, http.StatusBadRequest, "invalid_grant", err.Error())
+				return
+			}
+			if err != nil {
+				oauthError(ctx, http.StatusInternalServerError, "server_error", "token generation failed")
+				return
+			}
+			ctx.JSON(http.StatusOK, oauthTokenResponse(pair))
+		default:
+			oauthError(ctx, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant_type")
+		}
+	}
+}
+
+// requireClient authenticates the client and checks it may use the grant,
+// answering with the OAuth error when it may not.
+func requireClient(ctx *gin.Context, clients ClientFindRepository, input *OAuthTokenInput) (*model.Client, bool) {
+	client, ok := requireClientAuthentication(ctx, clients, input.ClientID, input.ClientSecret)
+	if !ok {
+		return nil, false
+	}
+
+	if !client.AllowsGrant(input.GrantType) {
+		oauthError(ctx, http.StatusBadRequest, "unauthorized_client", "grant type not allowed for the client")
+		return nil, false
+	}
+
+	return client, true
+}
+
+// requireClientAuthentication answers with invalid_client when the client
+// cannot be authenticated.
+func requireClientAuthentication(ctx *gin.Context, clients ClientFindRepository, clientID, clientSecret string) (*model.Client, bool) {
+	client, err := authenticateClient(ctx, clients, clientID, clientSecret)
+	if err != nil {
+		if _, _, basic := ctx.Request.BasicAuth(); basic {
+			ctx.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		oauthError(ctx, http.StatusUnauthorized, "invalid_client", err.Error())
+		return nil, false
+	}
+	return client, true
+}
+
+// authenticateClient identifies the client by HTTP Basic credentials or by
+// the client_id and client_secret form fields, RFC 6749 section 2.3.1.
+// Confidential clients must present their secret, public clients have none.
+func authenticateClient(ctx *gin.Context, clients ClientFindRepository, clientID, clientSecret string) (*model.Client, error) {
+	if username, password, ok := ctx.Request.BasicAuth(); ok {
+		basicID, err := url.QueryUnescape(username)
+		if err != nil {
+			return nil, errClientAuthentication
+		}
+		basicSecret, err := url.QueryUnescape(password)
+		if err != nil {
+			return nil, errClientAuthentication
+		}
+		if clientID != "" && clientID != basicID {
+			return nil, errClientAuthentication
+		}
+		clientID, clientSecret = basicID, basicSecret
+	}
+
+	if clientID == "" {
+		return nil, errClientAuthentication
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+	defer cancel()
+
+	client, err := clients.FindByClientID(reqCtx, clientID)
+	if err != nil {
+		return nil, errClientAuthentication
+	}
+
+	if client.Confidential() != (clientSecret != "") {
+		return nil, errClientAuthentication
+	}
+	if client.Confidential() && !client.CheckSecret(clientSecret) {
+		return nil, errClientAuthentication
+	}
+
+	return client, nil
+}
+
+func exchangeAuthorizationCode(
+	ctx *gin.Context,
+	client *model.Client,
+	users UserFindByUsernameRepository,
+	tokenConfig config.JWTConfig,
+	oidcConfig config.OIDCConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+	input OAuthTokenInput,
+) {
+	if input.Code == "" || input.RedirectURI == "" || input.CodeVerifier == "" {
+		oauthError(ctx, http.StatusBadRequest, "invalid_request", "code, redirect_uri and code_verifier are required")
+		return
+	}
+
+	record, err := consumeAuthorizationCode(cache, input.Code, refreshTokenTTL(tokenConfig))
+	if errors.Is(err, errAuthorizationCodeReused) || errors.Is(err, errAuthorizationCodeInvalid) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", err.Error())
+		return
+	}
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "could not redeem authorization code")
+		return
+	}
+
+	if record.ClientID != client.ClientID || record.RedirectURI != input.RedirectURI {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "authorization code was issued to another client")
+		return
+	}
+
+	if !verifyCodeChallenge(record.CodeChallenge, input.CodeVerifier) {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+		return
+	}
+
+	user, err := findUser(ctx.Request.Context(), users, cache, record.Username)
+	if err != nil || !sameAccount(user, record.UserID, record.TokenVersion) || !user.Active() {
+		oauthError(ctx, http.StatusBadRequest, "invalid_grant", errAuthorizationCodeInvalid.Error())
+		return
+	}
+
+	scopes := oidcScopes(keys, record.Scopes)
+	pair, err := issueTokenPair(cache, tokenConfig, keys, user, record.Family, tokenGrant{ClientID: client.ClientID, Scopes: scopes})
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "token generation failed")
+		return
+	}
+
+	response := oauthTokenResponse(pair)
+	if len(scopes) > 0 {
+		response["scope"] = strings.Join(scopes, " ")
+	}
+
+	if slices.Contains(scopes, scopeOpenID) {
+		idToken, err := generateIDToken(oidcConfig, tokenConfig, keys, user, record)
+		if err != nil {
+			oauthError(ctx, http.StatusInternalServerError, "server_error", "token generation failed")
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// issueClientCredentials answers the client credentials grant. No refresh
+// token is issued, the client can simply ask again, RFC 6749 section 4.4.3.
+func issueClientCredentials(
+	ctx *gin.Context,
+	client *model.Client,
+	tokenConfig config.JWTConfig,
+	keys *signing.KeyRing,
+	input OAuthTokenInput,
+) {
+	scopes, ok := client.GrantScopes(strings.Fields(input.Scope))
+	if !ok {
+		oauthError(ctx, http.StatusBadRequest, "invalid_scope", "scope not allowed for the client")
+		return
+	}
+
+	accessToken, err := generateClientToken(tokenConfig, keys, client, scopes)
+	if err != nil {
+		oauthError(ctx, http.StatusInternalServerError, "server_error", "token generation failed")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   tokenConfig.ExpirationMinutes * 60,
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+// oauthTokenResponse uses the field names of RFC 6749 section 5.1, /login
+// keeps its own.
+func oauthTokenResponse(pair *tokenPair) gin.H {
+	return gin.H{
+		"access_token":  pair.AccessToken,
+		"token_type":    pair.TokenType,
+		"expires_in":    pair.ExpiresIn,
+		"refresh_token": pair.RefreshToken,
+	}
+}
+
+// oauthError answers with an error code of RFC 6749 section 5.2 that OAuth
+// client libraries understand.
+func oauthError(ctx *gin.Context, status int, code, description string) {
+	ctx.JSON(status, gin.H{"error": code, "error_description": description})
+}
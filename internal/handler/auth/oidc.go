@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	scopeOpenID  = "openid"
+	scopeProfile = "profile"
+)
+
+// supportedUserScopes are the scopes a client can ask for on behalf of a
+// user. Others are dropped from the grant, RFC 6749 section 3.3.
+var supportedUserScopes = []string{scopeOpenID, scopeProfile}
+
+// oidcAvailable reports whether ID tokens can be issued. Relying parties
+// verify them with the keys of the JWKS, which holds no shared secrets, so
+// OpenID Connect needs an asymmetric signing key. Without one the openid
+// scope is not granted.
+func oidcAvailable(keys *signing.KeyRing) bool {
+	return !keys.CurrentIsSymmetric()
+}
+
+func oidcScopes(keys *signing.KeyRing, scopes []string) []string {
+	if oidcAvailable(keys) {
+		return scopes
+	}
+	return slices.DeleteFunc(slices.Clone(scopes), func(scope string) bool { return scope == scopeOpenID })
+}
+
+func grantedUserScopes(scope string) []string {
+	granted := []string{}
+	for _, requested := range strings.Fields(scope) {
+		if slices.Contains(supportedUserScopes, requested) && !slices.Contains(granted, requested) {
+			granted = append(granted, requested)
+		}
+	}
+	return granted
+}
+
+// subject is the OpenID Connect sub of the user. Usernames may be freed and
+// taken again, database ids are never reused.
+func subject(user *model.User) string {
+	return strconv.FormatUint(uint64(user.ID), 10)
+}
+
+// generateIDToken issues the OpenID Connect ID token for the client the
+// authorization code was redeemed by. It has no username claim, so AuthHandler
+// does not accept it as an access token.
+func generateIDToken(
+	oidcConfig config.OIDCConfig,
+	tokenConfig config.JWTConfig,
+	keys *signing.KeyRing,
+	user *model.User,
+	code *authorizationCode,
+) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       oidcConfig.Issuer,
+		"sub":       subject(user),
+		"aud":       code.ClientID,
+		"auth_time": code.AuthTime,
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Duration(tokenConfig.ExpirationMinutes) * time.Minute).Unix(),
+	}
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	if slices.Contains(code.Scopes, scopeProfile) {
+		claims["preferred_username"] = user.Username
+	}
+
+	return keys.Sign(claims)
+}
+
+// OpenIDConfigurationHandler serves the OpenID Connect discovery document,
+// so that standard client libraries can configure themselves.
+func OpenIDConfigurationHandler(oidcConfig config.OIDCConfig, keys *signing.KeyRing) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		issuer := oidcConfig.Issuer
+
+		ctx.Header("Cache-Control", "public, max-age=300")
+		document := gin.H{
+			"issuer":                                issuer,
+			"authorization_endpoint":                issuer + "/oauth/authorize",
+			"token_endpoint":                        issuer + "/oauth/token",
+			"userinfo_endpoint":                     issuer + "/userinfo",
+			"introspection_endpoint":                issuer + "/introspect",
+			"revocation_endpoint":                   issuer + "/revoke",
+			"jwks_uri":                              issuer + "/.well-known/jwks.json",
+			"scopes_supported":                      oidcScopes(keys, supportedUserScopes),
+			"response_types_supported":              []string{"code"},
+			"grant_types_supported":                 []string{model.GrantAuthorizationCode, model.GrantClientCredentials, "refresh_token"},
+			"subject_types_supported":               []string{"public"},
+			"token_endpoint_auth_methods_supported": []string{"none", "client_secret_basic", "client_secret_post"},
+			"code_challenge_methods_supported":      []string{"S256"},
+			"claims_supported":                      []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username"},
+		}
+		if oidcAvailable(keys) {
+			document["id_token_signing_alg_values_supported"] = []string{keys.CurrentAlgorithm()}
+		}
+		ctx.JSON(http.StatusOK, document)
+	}
+}
+
+// UserInfoHandler returns the claims about the signed in user, read from the
+// user repository so that they are current rather than those of the token.
+func UserInfoHandler(repo UserFindByUsernameRepository, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.GetString("username")
+		if username == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		user, err := findUser(ctx.Request.Context(), repo, cache, username)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			return
+		}
+
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusOK, gin.H{
+			"sub":                subject(user),
+			"preferred_username": user.Username,
+			"updated_at":         user.UpdatedAt.Unix(),
+		})
+	}
+}
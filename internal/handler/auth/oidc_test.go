@@ -0,0 +1,131 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/signing"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var oidcCfg = config.OIDCConfig{Issuer: "https://auth.example.com"}
+
+// newOIDCRouter signs with ES256, ID tokens need an asymmetric key.
+func newOIDCRouter(t *testing.T) (*gin.Engine, *signing.KeyRing) {
+	return newOIDCRouterWithKeys(t, newTestES256KeyRing(t, "oidc"))
+}
+
+func newOIDCRouterWithKeys(t *testing.T, keys *signing.KeyRing) (*gin.Engine, *signing.KeyRing) {
+	cache, _ := newInMemoryCache()
+	user := editor()
+	user.Model = gorm.Model{ID: 42, UpdatedAt: time.Unix(1700000000, 0)}
+	repo := &MockUserRepository{User: user}
+
+	r := newOAuthRouterWithKeys(t, repo, cache, keys)
+	r.GET("/.well-known/openid-configuration", auth.OpenIDConfigurationHandler(oidcCfg, keys))
+	r.GET("/userinfo", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg), auth.UserInfoHandler(repo, cache))
+	return r, keys
+}
+
+func discovery(t *testing.T, r *gin.Engine) map[string]any {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/openid-configuration", nil)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &document))
+	return document
+}
+
+func TestOpenIDConfigurationHandler(t *testing.T) {
+	r, _ := newOIDCRouter(t)
+
+	document := discovery(t, r)
+	assert.Equal(t, "https://auth.example.com", document["issuer"])
+	assert.Equal(t, "https://auth.example.com/oauth/token", document["token_endpoint"])
+	assert.Equal(t, "https://auth.example.com/.well-known/jwks.json", document["jwks_uri"])
+	assert.Equal(t, []any{"ES256"}, document["id_token_signing_alg_values_supported"])
+	assert.Equal(t, []any{"S256"}, document["code_challenge_methods_supported"])
+	assert.Contains(t, document["scopes_supported"], "openid")
+}
+
+func TestOIDC_RequiresAnAsymmetricKey(t *testing.T) {
+	r, _ := newOIDCRouterWithKeys(t, newTestKeyRing(t, testSecret))
+
+	document := discovery(t, r)
+	assert.NotContains(t, document["scopes_supported"], "openid")
+	assert.NotContains(t, document, "id_token_signing_alg_values_supported")
+
+	params := loginParams("alice", "secret")
+	params.Set("scope", "openid profile")
+	resp, body := exchangeCode(r, approve(t, r, params).Get("code"), testVerifier)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Nil(t, body["id_token"])
+	assert.Equal(t, "profile", body["scope"])
+}
+
+func TestOIDC_IDTokenIssuedForOpenIDScope(t *testing.T) {
+	r, keys := newOIDCRouter(t)
+
+	params := loginParams("alice", "secret")
+	params.Set("scope", "openid profile email")
+	params.Set("nonce", "n-0S6_WzA2Mj")
+	before := time.Now().Unix()
+	code := approve(t, r, params).Get("code")
+
+	resp, body := exchangeCode(r, code, testVerifier)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "openid profile", body["scope"])
+	require.NotEmpty(t, body["id_token"])
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(body["id_token"].(string), claims, keys.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com", claims["iss"])
+	assert.Equal(t, "42", claims["sub"])
+	assert.Equal(t, testClientID, claims["aud"])
+	assert.Equal(t, "n-0S6_WzA2Mj", claims["nonce"])
+	assert.Equal(t, "alice", claims["preferred_username"])
+	assert.GreaterOrEqual(t, claims["auth_time"].(float64), float64(before))
+
+	authResp := getAuth(r, body["id_token"].(string))
+	assert.Equal(t, http.StatusUnauthorized, authResp.Code, "an ID token is not an access token")
+}
+
+func TestOIDC_NoIDTokenWithoutOpenIDScope(t *testing.T) {
+	r, _ := newOIDCRouter(t)
+
+	code := approve(t, r, loginParams("alice", "secret")).Get("code")
+
+	resp, body := exchangeCode(r, code, testVerifier)
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Nil(t, body["id_token"])
+	assert.Nil(t, body["scope"])
+}
+
+func TestUserInfoHandler(t *testing.T) {
+	r, _ := newOIDCRouter(t)
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := getWithToken(r, "/userinfo", token)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var info map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &info))
+	assert.Equal(t, "42", info["sub"])
+	assert.Equal(t, "alice", info["preferred_username"])
+	assert.Equal(t, float64(1700000000), info["updated_at"])
+
+	assert.Equal(t, http.StatusUnauthorized, getWithToken(r, "/userinfo", "").Code)
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"Auth/internal/model"
+	"Auth/internal/passwords"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePasswordHandler lets an authenticated user set a new password after
+// confirming the current one. All of the user's tokens are revoked, so every
+// session, the current one included, has to log in again.
+func ChangePasswordHandler(repo UserPasswordRepository, passwordPolicy *passwords.Policy, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username := ctx.GetString("username")
+		if username == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		var input ChangePasswordInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.NewPassword == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, username)
+		if err != nil {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "user not found"})
+			return
+		}
+
+		if !user.CheckPassword(input.CurrentPassword) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		if !acceptPassword(ctx, passwordPolicy, input.NewPassword, user.Username) {
+			return
+		}
+
+		if err := updatePassword(repo, cache, user, input.NewPassword); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not update password"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "password changed"})
+	}
+}
+
+// updatePassword hashes and stores the new password and bumps the token
+// version, revoking every token and session issued with the old password.
+func updatePassword(repo UserUpdateRepository, cache Cache, user *model.User, password string) error {
+	user.Password = password
+	if err := user.SetPassword(); err != nil {
+		return err
+	}
+	user.TokenVersion++
+
+	if err := repo.Update(user); err != nil {
+		return err
+	}
+
+	if err := forgetUser(cache, user.Username); err != nil {
+		return err
+	}
+
+	dropUserSessions(cache, user.Username)
+	return nil
+}
+
+// acceptPassword answers 400 with every rule the password breaks when it
+// does not meet the policy. A nil policy accepts any password.
+func acceptPassword(ctx *gin.Context, passwordPolicy *passwords.Policy, password, username string) bool {
+	if passwordPolicy == nil {
+		return true
+	}
+
+	if violations := passwordPolicy.Check(password, username); len(violations) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "password does not meet the policy", "violations": violations})
+		return false
+	}
+	return true
+}
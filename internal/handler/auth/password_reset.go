@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"Auth/internal/notify"
+	"Auth/internal/passwords"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errResetTokenInvalid = errors.New("invalid or expired reset token")
+
+// resetNotifyTimeout bounds sending a reset token once the request that
+// asked for it has been answered.
+const resetNotifyTimeout = 30 * time.Second
+
+// resetTokenRecord is kept server side for every reset token. Like the
+// tokens of a login it is only good for the account at the version it was
+// issued for, a deleted account or one that logged out everywhere since
+// cannot be reset with it.
+type resetTokenRecord struct {
+	Username     string `json:"username"`
+	UserID       uint   `json:"user_id"`
+	TokenVersion int    `json:"token_version"`
+}
+
+// ForgotPasswordHandler sends a single-use reset token to the user. It
+// answers the same way whether the user exists or not, so it cannot be used
+// to enumerate accounts; the token is sent after the response, so neither
+// does the time it takes to answer.
+func ForgotPasswordHandler(
+	repo UserFindByUsernameRepository,
+	passwordConfig config.PasswordConfig,
+	cache Cache,
+	notifier notify.Notifier,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input ForgotPasswordInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Username == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		if user, err := repo.FindByUsername(reqCtx, input.Username); err == nil {
+			sendCtx := context.WithoutCancel(ctx.Request.Context())
+			go func() {
+				sendCtx, cancel := context.WithTimeout(sendCtx, resetNotifyTimeout)
+				defer cancel()
+
+				if err := sendPasswordReset(sendCtx, cache, notifier, passwordConfig, user); err != nil {
+					log.Printf("could not send password reset for %s: %v", user.Username, err)
+				}
+			}()
+		}
+
+		ctx.JSON(http.StatusAccepted, gin.H{"message": "if the account exists, a reset token has been sent"})
+	}
+}
+
+func ResetPasswordHandler(
+	repo UserPasswordRepository,
+	passwordConfig config.PasswordConfig,
+	passwordPolicy *passwords.Policy,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input ResetPasswordInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Token == "" || input.NewPassword == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		// the password is checked before the token is used up, so that a
+		// rejected password can be corrected with the same token
+		record, err := loadResetToken(cache, input.Token)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errResetTokenInvalid.Error()})
+			return
+		}
+		if !acceptPassword(ctx, passwordPolicy, input.NewPassword, record.Username) {
+			return
+		}
+
+		record, err = consumeResetToken(cache, input.Token, resetTokenTTL(passwordConfig))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errResetTokenInvalid.Error()})
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second)
+		defer cancel()
+
+		user, err := repo.FindByUsername(reqCtx, record.Username)
+		if err != nil || !sameAccount(user, record.UserID, record.TokenVersion) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": errResetTokenInvalid.Error()})
+			return
+		}
+
+		if err := updatePassword(repo, cache, user, input.NewPassword); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not update password"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "password reset"})
+	}
+}
+
+func sendPasswordReset(
+	ctx context.Context,
+	cache Cache,
+	notifier notify.Notifier,
+	passwordConfig config.PasswordConfig,
+	user *model.User,
+) error {
+	token, err := issueResetToken(cache, user, resetTokenTTL(passwordConfig))
+	if err != nil {
+		return err
+	}
+
+	return notifier.Notify(ctx, notify.Message{
+		To:      recipient(user),
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Use this token to reset your password: %s\nIt expires in %d minutes.",
+			token, passwordConfig.ResetExpirationMinutes,
+		),
+	})
+}
+
+func issueResetToken(cache Cache, user *model.User, ttl time.Duration) (string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	record, err := json.Marshal(resetTokenRecord{
+		Username:     user.Username,
+		UserID:       user.ID,
+		TokenVersion: user.TokenVersion,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := cache.Set(resetTokenKey(token), record, ttl); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func loadResetToken(cache Cache, token string) (*resetTokenRecord, error) {
+	val, err := cache.Get(resetTokenKey(token))
+	if err != nil || val == "" {
+		return nil, errResetTokenInvalid
+	}
+
+	var record resetTokenRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		return nil, errResetTokenInvalid
+	}
+	return &record, nil
+}
+
+// consumeResetToken returns the record of the token and makes sure the
+// token cannot be used again, even by a concurrent request.
+func consumeResetToken(cache Cache, token string, ttl time.Duration) (*resetTokenRecord, error) {
+	record, err := loadResetToken(cache, token)
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := cache.SetNX(resetTokenUsedKey(token), "used", ttl)
+	if err != nil || !first {
+		return nil, errResetTokenInvalid
+	}
+
+	_ = cache.Delete(resetTokenKey(token))
+
+	return record, nil
+}
+
+func resetTokenTTL(passwordConfig config.PasswordConfig) time.Duration {
+	return time.Duration(passwordConfig.ResetExpirationMinutes) * time.Minute
+}
+
+func resetTokenKey(token string) string {
+	return fmt.Sprintf("password_reset:%s", hashToken(token))
+}
+
+func resetTokenUsedKey(token string) string {
+	return fmt.Sprintf("password_reset_used:%s", hashToken(token))
+}
@@ -0,0 +1,151 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var passwordCfg = config.PasswordConfig{ResetExpirationMinutes: 30}
+
+func newPasswordResetRouter(repo *MockUserRepository, cache *MockCacheRepository, notifier *MockNotifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/password/forgot", auth.ForgotPasswordHandler(repo, passwordCfg, cache, notifier))
+	r.POST("/password/reset", auth.ResetPasswordHandler(repo, passwordCfg, testPasswordPolicy, cache))
+	return r
+}
+
+func newJSONRequest(method, path string, input any) *http.Request {
+	body, _ := json.Marshal(input)
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func postJSON(r *gin.Engine, path string, input any) *httptest.ResponseRecorder {
+	req := newJSONRequest(http.MethodPost, path, input)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+var resetTokenPattern = regexp.MustCompile(`reset your password: (\S+)`)
+
+// requestReset asks for a reset token for the user and returns the one sent.
+func requestReset(t *testing.T, r *gin.Engine, notifier *MockNotifier, username string) string {
+	t.Helper()
+	sent := len(notifier.waitForSent(t, 0))
+
+	resp := postJSON(r, "/password/forgot", auth.ForgotPasswordInput{Username: username})
+	require.Equal(t, http.StatusAccepted, resp.Code)
+
+	match := resetTokenPattern.FindStringSubmatch(notifier.waitForSent(t, sent+1)[sent].Body)
+	require.Len(t, match, 2)
+	return match[1]
+}
+
+func TestPasswordReset_Flow(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("forgotten"), TokenVersion: 3}}
+	notifier := &MockNotifier{}
+	r := newPasswordResetRouter(repo, cache, notifier)
+
+	token := requestReset(t, r, notifier, "alice")
+	assert.Equal(t, "alice", notifier.waitForSent(t, 1)[0].To)
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: token, NewPassword: "remembered"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.True(t, repo.User.CheckPassword("remembered"))
+	assert.Equal(t, 4, repo.User.TokenVersion)
+
+	resp = postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: token, NewPassword: "again"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid or expired reset token")
+	assert.True(t, repo.User.CheckPassword("remembered"))
+}
+
+func TestForgotPasswordHandler_UnknownUserLooksTheSame(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	notifier := &MockNotifier{}
+	r := newPasswordResetRouter(&MockUserRepository{}, cache, notifier)
+
+	resp := postJSON(r, "/password/forgot", auth.ForgotPasswordInput{Username: "nobody"})
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Empty(t, notifier.Sent)
+}
+
+func TestResetPasswordHandler_UnknownToken(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newPasswordResetRouter(&MockUserRepository{}, cache, &MockNotifier{})
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: "made-up", NewPassword: "whatever"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid or expired reset token")
+}
+
+func TestResetPasswordHandler_InvalidInput(t *testing.T) {
+	r := newPasswordResetRouter(&MockUserRepository{}, &MockCacheRepository{}, &MockNotifier{})
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: "token"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid input")
+}
+
+func TestResetPasswordHandler_RejectedPasswordKeepsToken(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("forgotten")}}
+	notifier := &MockNotifier{}
+	r := newPasswordResetRouter(repo, cache, notifier)
+
+	token := requestReset(t, r, notifier, "alice")
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: token, NewPassword: "short"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"rule":"min_length"`)
+
+	resp = postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: token, NewPassword: "remembered"})
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestResetPasswordHandler_TokenEndsWithTheTokenVersion(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("forgotten")}}
+	notifier := &MockNotifier{}
+	r := newPasswordResetRouter(repo, cache, notifier)
+
+	token := requestReset(t, r, notifier, "alice")
+	require.NoError(t, repo.IncrementTokenVersion("alice"))
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: token, NewPassword: "remembered"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid or expired reset token")
+	assert.True(t, repo.User.CheckPassword("forgotten"))
+}
+
+func TestResetPasswordHandler_TokenDoesNotCarryOverToANewAccount(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Model: gorm.Model{ID: 1}, Username: "alice", Password: hashPassword("forgotten")}}
+	notifier := &MockNotifier{}
+	r := newPasswordResetRouter(repo, cache, notifier)
+
+	token := requestReset(t, r, notifier, "alice")
+
+	// the account is deleted and someone else registers the username
+	repo.User = &model.User{Model: gorm.Model{ID: 2}, Username: "alice", Password: hashPassword("other")}
+
+	resp := postJSON(r, "/password/reset", auth.ResetPasswordInput{Token: token, NewPassword: "remembered"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.True(t, repo.User.CheckPassword("other"))
+}
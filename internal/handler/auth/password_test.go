@@ -0,0 +1,108 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPasswordRouter(t *testing.T, repo *MockUserRepository, cache *MockCacheRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	keys := newTestKeyRing(t, testSecret)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.PUT("/password", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg), auth.ChangePasswordHandler(repo, testPasswordPolicy, cache))
+	r.GET("/auth", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg))
+	return r
+}
+
+func putPassword(r *gin.Engine, token string, input auth.ChangePasswordInput) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(input)
+	req := httptest.NewRequest(http.MethodPut, "/password", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestChangePasswordHandler_Success(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("old-password")}}
+	r := newPasswordRouter(t, repo, cache)
+
+	token := login(t, r, "alice", "old-password")["token"].(string)
+
+	resp := putPassword(r, token, auth.ChangePasswordInput{CurrentPassword: "old-password", NewPassword: "new-password"})
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "password changed")
+
+	assert.True(t, repo.User.CheckPassword("new-password"))
+	assert.False(t, repo.User.CheckPassword("old-password"))
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, token).Code)
+
+	login(t, r, "alice", "new-password")
+}
+
+func TestChangePasswordHandler_WrongCurrentPassword(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("old-password")}}
+	r := newPasswordRouter(t, repo, cache)
+
+	token := login(t, r, "alice", "old-password")["token"].(string)
+
+	resp := putPassword(r, token, auth.ChangePasswordInput{CurrentPassword: "guess", NewPassword: "new-password"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid credentials")
+	assert.True(t, repo.User.CheckPassword("old-password"))
+}
+
+func TestChangePasswordHandler_MissingNewPassword(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("old-password")}}
+	r := newPasswordRouter(t, repo, cache)
+
+	token := login(t, r, "alice", "old-password")["token"].(string)
+
+	resp := putPassword(r, token, auth.ChangePasswordInput{CurrentPassword: "old-password"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestChangePasswordHandler_UpdateError(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{
+		User: &model.User{Username: "alice", Password: hashPassword("old-password")},
+		UpdateFunc: func(user *model.User) error {
+			return errors.New("db down")
+		},
+	}
+	r := newPasswordRouter(t, repo, cache)
+
+	token := login(t, r, "alice", "old-password")["token"].(string)
+
+	resp := putPassword(r, token, auth.ChangePasswordInput{CurrentPassword: "old-password", NewPassword: "new-password"})
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+}
+
+func TestChangePasswordHandler_RejectsPasswordBreakingPolicy(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("old-password")}}
+	r := newPasswordRouter(t, repo, cache)
+
+	token := login(t, r, "alice", "old-password")["token"].(string)
+
+	resp := putPassword(r, token, auth.ChangePasswordInput{CurrentPassword: "old-password", NewPassword: "alice-2024"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"rule":"username"`)
+	assert.True(t, repo.User.CheckPassword("old-password"))
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"Auth/internal/policy"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const policyDecisionKey = "policy_decision"
+
+// PolicyHandler evaluates the request Traefik forwards to /auth against the
+// access policy. It must run before AuthHandler: public requests are answered
+// right away so that they need no token, denied ones get 403 and for all
+// others the decision is left for AuthorizeHandler.
+func PolicyHandler(policies *policy.Engine) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		decision := policies.Evaluate(forwardedRequest(ctx))
+
+		switch decision.Access {
+		case policy.Public:
+			ctx.AbortWithStatus(http.StatusOK)
+			return
+		case policy.Deny:
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "denied by policy"})
+			return
+		}
+
+		ctx.Set(policyDecisionKey, decision)
+		ctx.Next()
+	}
+}
+
+// PolicyEvaluateHandler is a dry run of the policy: it tells which rule a
+// sample request matches and whether a caller with the given roles and
+// permissions would pass. A candidate policy sent along is evaluated instead
+// of the current one, so changes can be tried before they are deployed.
+func PolicyEvaluateHandler(policies *policy.Engine) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input PolicyEvaluateInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.Method == "" || input.Path == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+			return
+		}
+
+		req := policy.Request{Method: input.Method, Host: input.Host, Path: input.Path}
+
+		var decision policy.Decision
+		if len(input.Policy) > 0 {
+			candidate, err := policy.Parse(input.Policy)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			decision = candidate.Evaluate(req)
+		} else {
+			decision = policies.Evaluate(req)
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"decision": decision,
+			"allowed":  decision.Allows(input.Roles, input.Permissions),
+		})
+	}
+}
+
+// forwardedRequest reads the original request from the X-Forwarded-* headers
+// Traefik sets, falling back to the request itself when called directly.
+func forwardedRequest(ctx *gin.Context) policy.Request {
+	req := policy.Request{
+		Method: ctx.GetHeader("X-Forwarded-Method"),
+		Host:   ctx.GetHeader("X-Forwarded-Host"),
+		Path:   ctx.GetHeader("X-Forwarded-Uri"),
+	}
+
+	if req.Method == "" {
+		req.Method = ctx.Request.Method
+	}
+	if req.Host == "" {
+		req.Host = ctx.Request.Host
+	}
+	if req.Path == "" {
+		req.Path = ctx.Request.URL.EscapedPath()
+	}
+
+	return req
+}
@@ -0,0 +1,162 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"Auth/internal/policy"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const handlerTestPolicy = `{
+  "rules": [
+    { "name": "public", "path": "/public/**", "access": "public" },
+    { "name": "internal", "host": "internal.example.com", "access": "deny" },
+    { "name": "admin", "path": "/admin/**", "roles": ["admin"] },
+    { "name": "publish", "path": "/articles/*", "methods": ["POST"], "permissions": ["articles:publish"] }
+  ]
+}`
+
+func newPolicyRouter(t *testing.T, user *model.User) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: user}
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg)
+
+	p, err := policy.Parse([]byte(handlerTestPolicy))
+	require.NoError(t, err)
+	policies := policy.NewEngine(p)
+
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.GET("/auth", auth.PolicyHandler(policies), authenticated, auth.AuthorizeHandler(), auth.ForwardAuthHandler(defaultForwardAuthCfg))
+	r.POST("/admin/policy/evaluate", authenticated, auth.RequireRole("admin"), auth.PolicyEvaluateHandler(policies))
+	return r
+}
+
+func forwardAuth(r *gin.Engine, method, host, uri, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth", nil)
+	req.Header.Set("X-Forwarded-Method", method)
+	req.Header.Set("X-Forwarded-Host", host)
+	req.Header.Set("X-Forwarded-Uri", uri)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func policyAdmin() *model.User {
+	user := editor()
+	user.Roles = append(user.Roles, model.Role{Name: "admin"})
+	return user
+}
+
+func TestPolicyHandler_PublicRouteNeedsNoToken(t *testing.T) {
+	r := newPolicyRouter(t, editor())
+
+	resp := forwardAuth(r, "GET", "app.example.com", "/public/index.html", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("X-Auth-User"))
+}
+
+func TestPolicyHandler_DefaultRequiresToken(t *testing.T) {
+	r := newPolicyRouter(t, editor())
+
+	resp := forwardAuth(r, "GET", "app.example.com", "/dashboard", "")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	token := login(t, r, "alice", "secret")["token"].(string)
+	resp = forwardAuth(r, "GET", "app.example.com", "/dashboard", token)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "alice", resp.Header().Get("X-Auth-User"))
+}
+
+func TestPolicyHandler_DeniedHost(t *testing.T) {
+	r := newPolicyRouter(t, policyAdmin())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := forwardAuth(r, "GET", "internal.example.com", "/public/index.html", token)
+	assert.Equal(t, http.StatusOK, resp.Code, "the public rule comes first")
+
+	resp = forwardAuth(r, "GET", "internal.example.com", "/dashboard", token)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "denied by policy")
+}
+
+func TestPolicyHandler_RuleRequirements(t *testing.T) {
+	r := newPolicyRouter(t, editor())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	resp := forwardAuth(r, "GET", "app.example.com", "/admin/users", token)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "insufficient role")
+
+	for _, uri := range []string{"/%61dmin/users", "/admin%2Fusers", "/public/..%2Fadmin/users"} {
+		resp = forwardAuth(r, "GET", "app.example.com", uri, token)
+		assert.Equal(t, http.StatusForbidden, resp.Code, uri)
+	}
+
+	resp = forwardAuth(r, "POST", "app.example.com", "/articles/7", token)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+	assert.Contains(t, resp.Body.String(), "missing permission: articles:publish")
+
+	resp = forwardAuth(r, "GET", "app.example.com", "/articles/7", token)
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestPolicyEvaluateHandler(t *testing.T) {
+	r := newPolicyRouter(t, policyAdmin())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	evaluate := func(input map[string]any) (int, map[string]any) {
+		req := newJSONRequest(http.MethodPost, "/admin/policy/evaluate", input)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+
+		var body map[string]any
+		_ = json.Unmarshal(resp.Body.Bytes(), &body)
+		return resp.Code, body
+	}
+
+	code, body := evaluate(map[string]any{"method": "GET", "path": "/admin/users", "roles": []string{"editor"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "admin", body["decision"].(map[string]any)["rule"])
+	assert.Equal(t, false, body["allowed"])
+
+	code, body = evaluate(map[string]any{"method": "GET", "path": "/admin/users", "roles": []string{"admin"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["allowed"])
+
+	candidate := map[string]any{"rules": []any{map[string]any{"name": "open", "path": "/admin/**", "access": "public"}}}
+	code, body = evaluate(map[string]any{"method": "GET", "path": "/admin/users", "policy": candidate})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "open", body["decision"].(map[string]any)["rule"])
+	assert.Equal(t, "public", body["decision"].(map[string]any)["access"])
+
+	code, _ = evaluate(map[string]any{"method": "GET", "path": "/", "policy": map[string]any{"default": "maybe"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = evaluate(map[string]any{"path": "/"})
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestPolicyEvaluateHandler_RequiresAdmin(t *testing.T) {
+	r := newPolicyRouter(t, editor())
+	token := login(t, r, "alice", "secret")["token"].(string)
+
+	req := newJSONRequest(http.MethodPost, "/admin/policy/evaluate", map[string]any{"method": "GET", "path": "/"})
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"Auth/internal/identity"
+	"Auth/internal/ratelimit"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitBodyLimit bounds the body read to find the username or the
+// client_id before the request is authenticated. Requests to the rate
+// limited routes carry a few short fields.
+const rateLimitBodyLimit = 8 << 10
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// RateLimitHandler applies the rate limits configured for route. Every
+// rule of the route is counted, the request is rejected with 429 when any
+// of them is exceeded. The RateLimit-* headers describe the rule closest to
+// its limit. A nil limiter or a route without rules lets everything pass.
+func RateLimitHandler(limiter *ratelimit.Limiter, route string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if limiter == nil {
+			ctx.Next()
+			return
+		}
+
+		var tightest *ratelimit.Result
+		for _, rule := range limiter.Rules(route) {
+			value, err := rateLimitValue(ctx, rule.Key)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+				return
+			}
+			if value == "" {
+				continue
+			}
+
+			result, err := limiter.Allow(rule, value)
+			if err != nil {
+				log.Printf("rate limit of %s failed: %v", route, err)
+				continue
+			}
+
+			if !result.Allowed {
+				setRateLimitHeaders(ctx, result)
+				ctx.Header("Retry-After", retryAfter(result.RetryAfter))
+				ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests, try again later"})
+				return
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
+		}
+
+		if tightest != nil {
+			setRateLimitHeaders(ctx, *tightest)
+		}
+		ctx.Next()
+	}
+}
+
+func setRateLimitHeaders(ctx *gin.Context, result ratelimit.Result) {
+	ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit.Requests))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", retryAfter(result.Reset))
+	ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit.Requests, int(result.Limit.Window.Seconds())))
+}
+
+func rateLimitValue(ctx *gin.Context, key ratelimit.Key) (string, error) {
+	switch key {
+	case ratelimit.IP:
+		return ctx.ClientIP(), nil
+	case ratelimit.Username:
+		username, err := requestField(ctx, "username")
+		return identity.NormalizeUsername(username), err
+	case ratelimit.ClientID:
+		if clientID, _, ok := ctx.Request.BasicAuth(); ok {
+			return clientID, nil
+		}
+		return requestField(ctx, "client_id")
+	}
+	return "", nil
+}
+
+// requestField reads a field of a JSON or form body without consuming it,
+// the handler binds the body afterwards. Bodies over rateLimitBodyLimit
+// fail with errBodyTooLarge.
+func requestField(ctx *gin.Context, name string) (string, error) {
+	if ctx.Request.Body == nil {
+		return "", nil
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, rateLimitBodyLimit))
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return "", errBodyTooLarge
+	}
+	if err != nil {
+		return "", nil
+	}
+
+	if strings.HasPrefix(ctx.ContentType(), "application/x-www-form-urlencoded") {
+		values, _ := url.ParseQuery(string(body))
+		return values.Get(name), nil
+	}
+
+	var fields map[string]any
+	if json.Unmarshal(body, &fields) != nil {
+		return "", nil
+	}
+	value, _ := fields[name].(string)
+	return value, nil
+}
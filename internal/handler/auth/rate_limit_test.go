@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"Auth/internal/handler/auth"
+	"Auth/internal/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitRouter(t *testing.T, rules string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cache, _ := newInMemoryCache()
+	parsed, err := ratelimit.ParseRules(rules)
+	require.NoError(t, err)
+	limiter := ratelimit.NewLimiter(cache, parsed)
+
+	echo := func(ctx *gin.Context) {
+		var input auth.AuthInput
+		_ = ctx.ShouldBindJSON(&input)
+		ctx.JSON(http.StatusOK, gin.H{"username": input.Username})
+	}
+
+	r := gin.New()
+	r.POST("/login", auth.RateLimitHandler(limiter, "login"), echo)
+	r.POST("/oauth/token", auth.RateLimitHandler(limiter, "oauth_token"), echo)
+	r.POST("/register", auth.RateLimitHandler(nil, "register"), echo)
+	return r
+}
+
+func postFrom(r *gin.Engine, path, body, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":40000"
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestRateLimitHandler_RejectsWithHeaders(t *testing.T) {
+	r := newRateLimitRouter(t, "login:ip=2/1m")
+
+	resp := postFrom(r, "/login", `{"username":"alice"}`, "10.0.0.1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", resp.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", resp.Header().Get("RateLimit-Policy"))
+	assert.NotEmpty(t, resp.Header().Get("RateLimit-Reset"))
+
+	postFrom(r, "/login", `{"username":"alice"}`, "10.0.0.1")
+	resp = postFrom(r, "/login", `{"username":"alice"}`, "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+	retry, err := time.ParseDuration(resp.Header().Get("Retry-After") + "s")
+	require.NoError(t, err)
+	assert.Positive(t, retry)
+
+	assert.Equal(t, http.StatusOK, postFrom(r, "/login", `{"username":"alice"}`, "10.0.0.2").Code)
+}
+
+func TestRateLimitHandler_KeysByUsernameWithoutConsumingBody(t *testing.T) {
+	r := newRateLimitRouter(t, "login:username=1/1m")
+
+	resp := postFrom(r, "/login", `{"username":"alice"}`, "10.0.0.1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"username":"alice"`)
+
+	assert.Equal(t, http.StatusTooManyRequests, postFrom(r, "/login", `{"username":"alice"}`, "10.0.0.2").Code)
+	assert.Equal(t, http.StatusOK, postFrom(r, "/login", `{"username":"bob"}`, "10.0.0.1").Code)
+}
+
+func TestRateLimitHandler_KeysByClientID(t *testing.T) {
+	r := newRateLimitRouter(t, "oauth_token:client_id=1/1m")
+
+	form := func(clientID string) *httptest.ResponseRecorder {
+		body := url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}}.Encode()
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Equal(t, http.StatusOK, form("billing").Code)
+	assert.Equal(t, http.StatusTooManyRequests, form("billing").Code)
+	assert.Equal(t, http.StatusOK, form("reports").Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", nil)
+	req.SetBasicAuth("billing", "secret")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+}
+
+func TestRateLimitHandler_DisabledWithoutLimiter(t *testing.T) {
+	r := newRateLimitRouter(t, "")
+
+	for i := 0; i < 10; i++ {
+		resp := postFrom(r, "/register", `{}`, "10.0.0.1")
+		require.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitHandler_RefusesLargeBodies(t *testing.T) {
+	r := newRateLimitRouter(t, "login:ip=10/1m,login:username=10/1m")
+
+	body := `{"username":"alice","password":"` + strings.Repeat("a", 64<<10) + `"}`
+	resp := postFrom(r, "/login", body, "10.0.0.1")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.Code)
+
+	resp = postFrom(r, "/login", `{"username":"alice","password":"secret"}`, "10.0.0.1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "alice")
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/signing"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RefreshTokenHandler(
+	repo UserFindByUsernameRepository,
+	tokenConfig config.JWTConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var input RefreshInput
+		if err := ctx.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "corrupted input payload"})
+			return
+		}
+
+		// tokens issued to OAuth clients are refreshed at /oauth/token
+		pair, err := rotateRefreshToken(ctx.Request.Context(), repo, tokenConfig, keys, cache, input.RefreshToken, "")
+		if errors.Is(err, errRefreshTokenReused) || errors.Is(err, errRefreshTokenInvalid) || errors.Is(err, errRefreshTokenClient) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, pair)
+	}
+}
+
+// rotateRefreshToken consumes the refresh token of the client and issues a
+// new pair in the same family. It fails with errRefreshTokenReused,
+// errRefreshTokenInvalid or errRefreshTokenClient when the token cannot be
+// exchanged.
+func rotateRefreshToken(
+	ctx context.Context,
+	repo UserFindByUsernameRepository,
+	tokenConfig config.JWTConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+	refreshToken string,
+	clientID string,
+) (*tokenPair, error) {
+	record, err := consumeRefreshToken(cache, refreshToken, clientID, refreshTokenTTL(tokenConfig))
+	if errors.Is(err, errRefreshTokenReused) || errors.Is(err, errRefreshTokenClient) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errRefreshTokenInvalid
+	}
+
+	user, err := findUser(ctx, repo, cache, record.Username)
+	if err != nil || !sameAccount(user, record.UserID, record.TokenVersion) || !user.Active() {
+		_ = revokeRefreshFamily(cache, record.Family, refreshTokenTTL(tokenConfig))
+		return nil, errRefreshTokenInvalid
+	}
+
+	if record.SessionID != "" {
+		if session, err := loadSession(cache, record.SessionID); err == nil {
+			session.LastSeenAt = time.Now()
+			_ = extendTokenSession(cache, tokenConfig, session)
+		}
+	}
+
+	return issueTokenPair(cache, tokenConfig, keys, user, record.Family, record.tokenGrant)
+}
@@ -0,0 +1,147 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var refreshTokenCfg = config.JWTConfig{
+	ExpirationMinutes:        15,
+	RefreshExpirationMinutes: 60,
+}
+
+func login(t *testing.T, r *gin.Engine, username, password string) map[string]any {
+	t.Helper()
+	body, _ := json.Marshal(auth.AuthInput{Username: username, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	return out
+}
+
+func refresh(r *gin.Engine, refreshToken string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(auth.RefreshInput{RefreshToken: refreshToken})
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func newRefreshRouter(t *testing.T, repo *MockUserRepository, cache *MockCacheRepository) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	keys := newTestKeyRing(t, testSecret)
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, keys, cache))
+	r.POST("/token/refresh", auth.RefreshTokenHandler(repo, refreshTokenCfg, keys, cache))
+	return r
+}
+
+func TestRefreshTokenHandler_RotatesToken(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newRefreshRouter(t, repo, cache)
+
+	pair := login(t, r, "alice", "secret")
+	assert.NotEmpty(t, pair["token"])
+	assert.NotEmpty(t, pair["refresh_token"])
+
+	resp := refresh(r, pair["refresh_token"].(string))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var rotated map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &rotated))
+	assert.NotEmpty(t, rotated["token"])
+	assert.NotEqual(t, pair["refresh_token"], rotated["refresh_token"])
+
+	resp = refresh(r, rotated["refresh_token"].(string))
+	assert.Equal(t, http.StatusOK, resp.Code)
+}
+
+func TestRefreshTokenHandler_ReuseRevokesFamily(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newRefreshRouter(t, repo, cache)
+
+	pair := login(t, r, "alice", "secret")
+
+	resp := refresh(r, pair["refresh_token"].(string))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var rotated map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &rotated))
+
+	resp = refresh(r, pair["refresh_token"].(string))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "refresh token reuse detected")
+
+	resp = refresh(r, rotated["refresh_token"].(string))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid refresh token")
+}
+
+func TestRefreshTokenHandler_ReuseDoesNotAffectOtherFamilies(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newRefreshRouter(t, repo, cache)
+
+	first := login(t, r, "alice", "secret")
+	second := login(t, r, "alice", "secret")
+
+	require.Equal(t, http.StatusOK, refresh(r, first["refresh_token"].(string)).Code)
+	require.Equal(t, http.StatusUnauthorized, refresh(r, first["refresh_token"].(string)).Code)
+
+	assert.Equal(t, http.StatusOK, refresh(r, second["refresh_token"].(string)).Code)
+}
+
+func TestRefreshTokenHandler_UnknownToken(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	r := newRefreshRouter(t, &MockUserRepository{}, cache)
+
+	resp := refresh(r, "does-not-exist")
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid refresh token")
+}
+
+func TestRefreshTokenHandler_UserDeleted(t *testing.T) {
+	cache, store := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Username: "alice", Password: hashPassword("secret")}}
+	r := newRefreshRouter(t, repo, cache)
+
+	pair := login(t, r, "alice", "secret")
+	delete(store, "user:alice")
+	repo.FindByUsernameFunc = func(ctx context.Context, username string) (*model.User, error) {
+		return nil, errors.New("not found")
+	}
+
+	resp := refresh(r, pair["refresh_token"].(string))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestRefreshTokenHandler_InvalidJSON(t *testing.T) {
+	r := newRefreshRouter(t, &MockUserRepository{}, &MockCacheRepository{})
+
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewReader([]byte("not-json")))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "corrupted input")
+}
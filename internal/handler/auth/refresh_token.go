@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("invalid refresh token")
+	errRefreshTokenReused  = errors.New("refresh token reuse detected")
+	errRefreshTokenClient  = errors.New("refresh token was issued to another client")
+)
+
+// refreshTokenRecord is what we keep server side for every issued refresh
+// token. The token itself is never stored, only its sha256 digest is used
+// as the key.
+type refreshTokenRecord struct {
+	Username     string `json:"username"`
+	UserID       uint   `json:"user_id"`
+	Family       string `json:"family"`
+	TokenVersion int    `json:"token_version"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"`
+	tokenGrant
+}
+
+func refreshTokenTTL(tokenConfig config.JWTConfig) time.Duration {
+	return time.Duration(tokenConfig.RefreshExpirationMinutes) * time.Minute
+}
+
+func newRefreshFamily() (string, error) {
+	return newTokenID()
+}
+
+func issueRefreshToken(cache Cache, user *model.User, family string, grant tokenGrant, ttl time.Duration) (string, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	record, err := json.Marshal(refreshTokenRecord{
+		Username:     user.Username,
+		UserID:       user.ID,
+		Family:       family,
+		TokenVersion: user.TokenVersion,
+		ExpiresAt:    time.Now().Add(ttl).Unix(),
+		tokenGrant:   grant,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := cache.Set(refreshTokenKey(token), record, ttl); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// consumeRefreshToken marks the token as used and returns its record. A token
+// can be consumed only once; presenting it again revokes its whole family, so
+// both the attacker and the legitimate client holding the rotated token are
+// forced to log in again. Only the client the token was issued to, none for
+// tokens from /login, may consume it; others are refused without using it up.
+func consumeRefreshToken(cache Cache, token, clientID string, ttl time.Duration) (*refreshTokenRecord, error) {
+	val, err := cache.Get(refreshTokenKey(token))
+	if err != nil || val == "" {
+		return nil, errRefreshTokenInvalid
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		return nil, errRefreshTokenInvalid
+	}
+
+	if revoked, err := cache.Get(refreshFamilyKey(record.Family)); err == nil && revoked != "" {
+		return nil, errRefreshTokenInvalid
+	}
+
+	if record.ClientID != clientID {
+		return nil, errRefreshTokenClient
+	}
+
+	first, err := cache.SetNX(refreshTokenUsedKey(token), "used", ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if !first {
+		_ = revokeRefreshFamily(cache, record.Family, ttl)
+		return nil, errRefreshTokenReused
+	}
+
+	return &record, nil
+}
+
+// activeRefreshToken returns the record of a refresh token that can still be
+// exchanged, without consuming it.
+func activeRefreshToken(cache Cache, token string) (*refreshTokenRecord, bool) {
+	val, err := cache.Get(refreshTokenKey(token))
+	if err != nil || val == "" {
+		return nil, false
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		return nil, false
+	}
+
+	if used, err := cache.Get(refreshTokenUsedKey(token)); err == nil && used != "" {
+		return nil, false
+	}
+	if revoked, err := cache.Get(refreshFamilyKey(record.Family)); err == nil && revoked != "" {
+		return nil, false
+	}
+
+	return &record, true
+}
+
+// revokeRefreshToken revokes the family the token belongs to. Unknown tokens
+// are ignored, there is nothing left to revoke.
+func revokeRefreshToken(cache Cache, token string, ttl time.Duration) error {
+	val, err := cache.Get(refreshTokenKey(token))
+	if err != nil || val == "" {
+		return nil
+	}
+
+	var record refreshTokenRecord
+	if err := json.Unmarshal([]byte(val), &record); err != nil {
+		return nil
+	}
+
+	return revokeRefreshFamily(cache, record.Family, ttl)
+}
+
+func revokeRefreshFamily(cache Cache, family string, ttl time.Duration) error {
+	return cache.Set(refreshFamilyKey(family), "revoked", ttl)
+}
+
+func refreshTokenKey(token string) string {
+	return fmt.Sprintf("refresh_token:%s", hashToken(token))
+}
+
+func refreshTokenUsedKey(token string) string {
+	return fmt.Sprintf("refresh_token_used:%s", hashToken(token))
+}
+
+func refreshFamilyKey(family string) string {
+	return fmt.Sprintf("refresh_family:%s", family)
+}
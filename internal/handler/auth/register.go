@@ -2,24 +2,65 @@ package auth
 
 import (
 	"Auth/config"
+	"Auth/internal/identity"
 	"Auth/internal/model"
-	"encoding/json"
-	"fmt"
+	"Auth/internal/notify"
+	"Auth/internal/passwords"
+	"Auth/internal/signing"
+	"log"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func RegisterHandler(repo UserCreateRepository, tokenConfig config.JWTConfig, cache Cache) gin.HandlerFunc {
+func RegisterHandler(
+	repo UserCreateRepository,
+	tokenConfig config.JWTConfig,
+	emailConfig config.EmailVerificationConfig,
+	usernamePolicy *identity.UsernamePolicy,
+	passwordPolicy *passwords.Policy,
+	keys *signing.KeyRing,
+	cache Cache,
+	notifier notify.Notifier,
+) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var input AuthInput
+		var input RegisterInput
 		if err := ctx.ShouldBindJSON(&input); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
 			return
 		}
 
+		username := identity.NormalizeUsername(input.Username)
+		if username == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "username is required"})
+			return
+		}
+		if usernamePolicy != nil {
+			var err error
+			if username, err = usernamePolicy.Check(input.Username); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var email string
+		if input.Email != "" {
+			var err error
+			if email, err = identity.NormalizeEmail(input.Email); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if email == "" && emailConfig.Required {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+			return
+		}
+
+		if !acceptPassword(ctx, passwordPolicy, input.Password, username) {
+			return
+		}
+
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "hash error"})
@@ -27,8 +68,13 @@ func RegisterHandler(repo UserCreateRepository, tokenConfig config.JWTConfig, ca
 		}
 
 		user := model.User{
-			Username: input.Username,
+			Username: username,
+			Email:    email,
 			Password: string(hashedPassword),
+			Status:   model.UserStatusActive,
+		}
+		if emailConfig.Required {
+			user.Status = model.UserStatusPendingVerification
 		}
 
 		if err := repo.Create(&user); err != nil {
@@ -36,16 +82,15 @@ func RegisterHandler(repo UserCreateRepository, tokenConfig config.JWTConfig, ca
 			return
 		}
 
-		userJSON, err := json.Marshal(user)
-		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not serialize user"})
+		if err := cacheUser(cache, &user); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not save to redis"})
 			return
 		}
 
-		redisKey := fmt.Sprintf("user:%s", user.Username)
-		if err := cache.Set(redisKey, userJSON, time.Hour*24); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not save to redis"})
-			return
+		if user.Email != "" && notifier != nil {
+			if err := sendEmailVerification(ctx.Request.Context(), emailConfig, keys, notifier, &user); err != nil {
+				log.Printf("could not send email verification for %s: %v", user.Username, err)
+			}
 		}
 
 		ctx.JSON(http.StatusCreated, gin.H{"message": "user created"})
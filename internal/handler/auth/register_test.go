@@ -3,7 +3,9 @@ package auth_test
 import (
 	"Auth/config"
 	"Auth/internal/handler/auth"
+	"Auth/internal/identity"
 	"Auth/internal/model"
+	"Auth/internal/passwords"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -14,8 +16,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var testPasswordPolicy = &passwords.Policy{MinLength: 8, MaxBytes: 72, MinClasses: 1, DisallowUsername: true}
+
 func TestRegisterHandler_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -35,18 +40,21 @@ func TestRegisterHandler_Success(t *testing.T) {
 		},
 	}
 
+	var cachedFor time.Duration
 	cache := &MockCacheRepository{
 		SetFunc: func(key string, value any, expiration time.Duration) error {
+			cachedFor = expiration
 			return nil
 		},
 	}
 
 	router := gin.Default()
-	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, cache))
+	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, config.EmailVerificationConfig{}, nil, nil, nil, cache, nil))
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusCreated, resp.Code)
 	assert.Contains(t, resp.Body.String(), "user created")
+	assert.Equal(t, 5*time.Minute, cachedFor, "cached like any other user")
 }
 
 func TestRegisterHandler_InvalidJSON(t *testing.T) {
@@ -57,7 +65,7 @@ func TestRegisterHandler_InvalidJSON(t *testing.T) {
 	resp := httptest.NewRecorder()
 
 	router := gin.Default()
-	router.POST("/register", auth.RegisterHandler(&MockUserRepository{}, config.JWTConfig{}, &MockCacheRepository{}))
+	router.POST("/register", auth.RegisterHandler(&MockUserRepository{}, config.JWTConfig{}, config.EmailVerificationConfig{}, nil, nil, nil, &MockCacheRepository{}, nil))
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusBadRequest, resp.Code)
@@ -84,7 +92,7 @@ func TestRegisterHandler_UserAlreadyExists(t *testing.T) {
 	}
 
 	router := gin.Default()
-	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, &MockCacheRepository{}))
+	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, config.EmailVerificationConfig{}, nil, nil, nil, &MockCacheRepository{}, nil))
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusConflict, resp.Code)
@@ -117,9 +125,89 @@ func TestRegisterHandler_CacheError(t *testing.T) {
 	}
 
 	router := gin.Default()
-	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, cache))
+	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, config.EmailVerificationConfig{}, nil, nil, nil, cache, nil))
 	router.ServeHTTP(resp, req)
 
 	assert.Equal(t, http.StatusInternalServerError, resp.Code)
 	assert.Contains(t, resp.Body.String(), "could not save to redis")
 }
+
+func TestRegisterHandler_PasswordPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	created := false
+	repo := &MockUserRepository{
+		CreateFunc: func(user *model.User) error {
+			created = true
+			return nil
+		},
+	}
+	router := gin.New()
+	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, config.EmailVerificationConfig{}, nil, testPasswordPolicy, nil, &MockCacheRepository{}, nil))
+
+	body, _ := json.Marshal(auth.AuthInput{Username: "testuser", Password: "testuser"})
+	req, _ := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	var out struct {
+		Error      string                `json:"error"`
+		Violations []passwords.Violation `json:"violations"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	assert.Equal(t, "password does not meet the policy", out.Error)
+	require.Len(t, out.Violations, 1)
+	assert.Equal(t, "username", out.Violations[0].Rule)
+	assert.False(t, created)
+}
+
+func TestRegisterHandler_EmptyUsername(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	body, _ := json.Marshal(auth.AuthInput{Password: "long enough"})
+	req, _ := http.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	router := gin.New()
+	router.POST("/register", auth.RegisterHandler(&MockUserRepository{}, config.JWTConfig{}, config.EmailVerificationConfig{}, nil, testPasswordPolicy, nil, &MockCacheRepository{}, nil))
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "username is required")
+}
+
+func TestRegisterHandler_NormalizesUsernameAndEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	usernamePolicy, err := identity.NewUsernamePolicy(config.UsernameConfig{MinLength: 3, MaxLength: 32, Pattern: `^[\p{L}\p{N}._-]+$`})
+	require.NoError(t, err)
+	cache, store := newInMemoryCache()
+	repo := &MockUserRepository{}
+	repo.CreateFunc = func(user *model.User) error {
+		repo.User = user
+		return nil
+	}
+
+	router := gin.New()
+	router.POST("/register", auth.RegisterHandler(repo, config.JWTConfig{}, config.EmailVerificationConfig{}, usernamePolicy, testPasswordPolicy, nil, cache, nil))
+	router.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionCfg, newTestKeyRing(t, testSecret), cache))
+
+	resp := postJSON(router, "/register", auth.RegisterInput{Username: " ＡLICE ", Password: "long enough", Email: "Alice@Example.com"})
+	require.Equal(t, http.StatusCreated, resp.Code, resp.Body.String())
+	assert.Equal(t, "alice", repo.User.Username)
+	assert.Equal(t, "alice@example.com", repo.User.Email)
+	assert.Contains(t, store, "user:alice")
+
+	login(t, router, "Alice", "long enough")
+
+	resp = postJSON(router, "/register", auth.RegisterInput{Username: "user:bob", Password: "long enough"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "not allowed")
+
+	resp = postJSON(router, "/register", auth.RegisterInput{Username: "bob", Password: "long enough", Email: "bob"})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "invalid email address")
+}
@@ -0,0 +1,418 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/identity"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errSessionInvalid = errors.New("invalid session")
+	errSessionExpired = errors.New("session expired")
+	errSessionRevoked = errors.New("session has been revoked")
+)
+
+// Every login starts a session. Cookie sessions are what the session cookie
+// refers to, token sessions group the tokens of one refresh token family and
+// share its ID.
+const (
+	cookieSession = "cookie"
+	tokenSession  = "token"
+)
+
+// sessionTouchInterval limits how often the last use of a token session is
+// written, so that /auth does not write to Redis on every request.
+const sessionTouchInterval = time.Minute
+
+// sessionRecord is kept server side for every session. Like refresh tokens
+// the session cookie is never stored, cookie sessions are keyed by its
+// sha256 digest, which also serves as their ID.
+type sessionRecord struct {
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	Username     string    `json:"username"`
+	UserID       uint      `json:"user_id"`
+	TokenVersion int       `json:"token_version"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastSeenAt   time.Time `json:"last_seen_at"`
+	IP           string    `json:"ip"`
+	UserAgent    string    `json:"user_agent"`
+	// CSRFToken is the synchronizer token of a cookie session, see checkCSRF.
+	CSRFToken string `json:"csrf_token,omitempty"`
+	// RefreshExpiresAt is when the refresh token of a token session
+	// expires, every refresh extends it.
+	RefreshExpiresAt time.Time `json:"refresh_expires_at,omitzero"`
+}
+
+// expiresAt is when the session ends unless it is used again before.
+func (s *sessionRecord) expiresAt(sessionConfig config.SessionConfig) time.Time {
+	if s.Type == tokenSession {
+		return s.RefreshExpiresAt
+	}
+
+	idle := s.LastSeenAt.Add(time.Duration(sessionConfig.IdleTimeoutMinutes) * time.Minute)
+	absolute := s.CreatedAt.Add(time.Duration(sessionConfig.AbsoluteTimeoutMinutes) * time.Minute)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+func newSession(ctx *gin.Context, id, sessionType string, user *model.User) *sessionRecord {
+	now := time.Now()
+	return &sessionRecord{
+		ID:           id,
+		Type:         sessionType,
+		Username:     user.Username,
+		UserID:       user.ID,
+		TokenVersion: user.TokenVersion,
+		CreatedAt:    now,
+		LastSeenAt:   now,
+		IP:           ctx.ClientIP(),
+		UserAgent:    ctx.Request.UserAgent(),
+	}
+}
+
+// startSession creates a cookie session for the user and sets its cookie.
+func startSession(ctx *gin.Context, sessionConfig config.SessionConfig, cache Cache, user *model.User) (*sessionRecord, error) {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	csrfToken, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	absolute := time.Duration(sessionConfig.AbsoluteTimeoutMinutes) * time.Minute
+	session := newSession(ctx, hashToken(token), cookieSession, user)
+	session.CSRFToken = csrfToken
+	if err := saveSession(cache, sessionConfig, session); err != nil {
+		return nil, err
+	}
+	if err := cache.AddToSet(userSessionsKey(user.Username), session.ID, absolute); err != nil {
+		return nil, err
+	}
+
+	setSessionCookie(ctx, sessionConfig, token, absolute)
+	setCSRFCookie(ctx, sessionConfig, csrfToken, absolute)
+	return session, nil
+}
+
+// startTokenSession records the session of a token login, the refresh
+// family is its ID.
+func startTokenSession(ctx *gin.Context, tokenConfig config.JWTConfig, cache Cache, user *model.User, family string) error {
+	session := newSession(ctx, family, tokenSession, user)
+	return extendTokenSession(cache, tokenConfig, session)
+}
+
+// extendTokenSession keeps a token session for as long as its latest
+// refresh token.
+func extendTokenSession(cache Cache, tokenConfig config.JWTConfig, session *sessionRecord) error {
+	ttl := refreshTokenTTL(tokenConfig)
+	session.RefreshExpiresAt = time.Now().Add(ttl)
+	if err := saveSession(cache, config.SessionConfig{}, session); err != nil {
+		return err
+	}
+	return cache.AddToSet(userSessionsKey(session.Username), session.ID, ttl)
+}
+
+// touchSession returns the session of the cookie and extends its idle
+// timeout. Sessions of users that logged out everywhere since are ended,
+// requests failing the CSRF check are refused without counting as use.
+func touchSession(ctx *gin.Context, sessionConfig config.SessionConfig, cache Cache, repo UserFindByUsernameRepository, token string) (*sessionRecord, *model.User, error) {
+	session, err := loadSession(cache, hashToken(token))
+	if err != nil || session.Type != cookieSession {
+		return nil, nil, errSessionInvalid
+	}
+
+	now := time.Now()
+	if !now.Before(session.expiresAt(sessionConfig)) {
+		_ = dropSession(cache, session)
+		return nil, nil, errSessionExpired
+	}
+
+	user, err := findUser(ctx.Request.Context(), repo, cache, session.Username)
+	if err != nil || !sameAccount(user, session.UserID, session.TokenVersion) || !user.Active() {
+		_ = dropSession(cache, session)
+		return nil, nil, errSessionRevoked
+	}
+
+	if err := checkCSRF(ctx, sessionConfig.CSRF, session); err != nil {
+		return nil, nil, err
+	}
+
+	session.LastSeenAt = now
+	session.IP = ctx.ClientIP()
+	if err := saveSession(cache, sessionConfig, session); err != nil {
+		return nil, nil, err
+	}
+
+	return session, user, nil
+}
+
+// touchTokenSession records the use of a token session. Tokens do not
+// depend on their session record, a missing one is ignored.
+func touchTokenSession(ctx *gin.Context, cache Cache, id string) {
+	session, err := loadSession(cache, id)
+	if err != nil || session.Type != tokenSession || time.Since(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+
+	session.LastSeenAt = time.Now()
+	session.IP = ctx.ClientIP()
+	_ = saveSession(cache, config.SessionConfig{}, session)
+}
+
+func loadSession(cache Cache, id string) (*sessionRecord, error) {
+	val, err := cache.Get(sessionKey(id))
+	if err != nil || val == "" {
+		return nil, errSessionInvalid
+	}
+
+	var session sessionRecord
+	if err := json.Unmarshal([]byte(val), &session); err != nil {
+		return nil, errSessionInvalid
+	}
+	return &session, nil
+}
+
+// saveSession stores the session until its expiry, so that Redis drops
+// idle sessions by itself.
+func saveSession(cache Cache, sessionConfig config.SessionConfig, session *sessionRecord) error {
+	record, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return cache.Set(sessionKey(session.ID), record, time.Until(session.expiresAt(sessionConfig)))
+}
+
+// listSessions returns the live sessions of the user, most recently used
+// first, and drops the ones that expired from the index.
+func listSessions(cache Cache, username string) ([]*sessionRecord, error) {
+	ids, err := cache.SetMembers(userSessionsKey(username))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []*sessionRecord{}
+	for _, id := range ids {
+		session, err := loadSession(cache, id)
+		if err != nil {
+			_ = cache.RemoveFromSet(userSessionsKey(username), id)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	slices.SortFunc(sessions, func(a, b *sessionRecord) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// revokeSession ends the session. The refresh family of a token session is
+// revoked and the session blacklisted, so that its access tokens are
+// refused right away instead of when they expire.
+func revokeSession(cache Cache, tokenConfig config.JWTConfig, session *sessionRecord) error {
+	if err := dropSession(cache, session); err != nil {
+		return err
+	}
+	if session.Type != tokenSession {
+		return nil
+	}
+
+	if err := revokeRefreshFamily(cache, session.ID, refreshTokenTTL(tokenConfig)); err != nil {
+		return err
+	}
+	return cache.Set(sessionBlacklistKey(session.ID), "invalid", time.Duration(tokenConfig.ExpirationMinutes)*time.Minute)
+}
+
+func dropSession(cache Cache, session *sessionRecord) error {
+	if err := cache.Delete(sessionKey(session.ID)); err != nil {
+		return err
+	}
+	return cache.RemoveFromSet(userSessionsKey(session.Username), session.ID)
+}
+
+// endSession revokes the session the request was authenticated with.
+func endSession(ctx *gin.Context, tokenConfig config.JWTConfig, sessionConfig config.SessionConfig, cache Cache, id string) error {
+	session, err := loadSession(cache, id)
+	if err != nil {
+		return nil
+	}
+	if session.Type == cookieSession {
+		clearSessionCookie(ctx, sessionConfig)
+	}
+	return revokeSession(cache, tokenConfig, session)
+}
+
+func sessionKey(id string) string {
+	return fmt.Sprintf("session:%s", id)
+}
+
+func userSessionsKey(username string) string {
+	return fmt.Sprintf("user_sessions:%s", identity.NormalizeUsername(username))
+}
+
+// sessionBlacklistKey shares the namespace of blacklisted tokens, the
+// access tokens of a revoked session are refused like blacklisted ones.
+func sessionBlacklistKey(id string) string {
+	return blacklistKey("session:" + id)
+}
+
+// sessionCookie returns the session cookie of the request, empty when
+// sessions are disabled.
+func sessionCookie(ctx *gin.Context, sessionConfig config.SessionConfig) string {
+	if !sessionConfig.Enabled {
+		return ""
+	}
+	token, _ := ctx.Cookie(sessionConfig.CookieName)
+	return token
+}
+
+func setSessionCookie(ctx *gin.Context, sessionConfig config.SessionConfig, token string, maxAge time.Duration) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     sessionConfig.CookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   sessionConfig.CookieDomain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   sessionConfig.Secure,
+		HttpOnly: true,
+		SameSite: sameSite(sessionConfig.SameSite),
+	})
+}
+
+func clearSessionCookie(ctx *gin.Context, sessionConfig config.SessionConfig) {
+	setSessionCookie(ctx, sessionConfig, "", -time.Second)
+	setCSRFCookie(ctx, sessionConfig, "", -time.Second)
+}
+
+func sameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// respondLogin finishes a successful login, with a session cookie when the
+// client asked for one and a token pair otherwise.
+func respondLogin(
+	ctx *gin.Context,
+	tokenConfig config.JWTConfig,
+	sessionConfig config.SessionConfig,
+	keys *signing.KeyRing,
+	cache Cache,
+	user *model.User,
+	session bool,
+) {
+	if session {
+		record, err := startSession(ctx, sessionConfig, cache, user)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "session creation failed"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":    "logged in",
+			"expires_at": record.expiresAt(sessionConfig).Unix(),
+			"csrf_token": record.CSRFToken,
+		})
+		return
+	}
+
+	family, err := newRefreshFamily()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+
+	if err := startTokenSession(ctx, tokenConfig, cache, user, family); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "session creation failed"})
+		return
+	}
+
+	pair, err := issueTokenPair(cache, tokenConfig, keys, user, family, tokenGrant{SessionID: family})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pair)
+}
+
+// sessionView is how sessions are listed to their user.
+type sessionView struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// SessionListHandler lists where the user is logged in.
+func SessionListHandler(sessionConfig config.SessionConfig, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		sessions, err := listSessions(cache, ctx.GetString("username"))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not list sessions"})
+			return
+		}
+
+		views := make([]sessionView, 0, len(sessions))
+		for _, session := range sessions {
+			views = append(views, sessionView{
+				ID:         session.ID,
+				Type:       session.Type,
+				IP:         session.IP,
+				UserAgent:  session.UserAgent,
+				CreatedAt:  session.CreatedAt,
+				LastSeenAt: session.LastSeenAt,
+				ExpiresAt:  session.expiresAt(sessionConfig),
+				Current:    session.ID == ctx.GetString("session_id"),
+			})
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"sessions": views})
+	}
+}
+
+// SessionRevokeHandler logs the user out of one of their sessions.
+func SessionRevokeHandler(tokenConfig config.JWTConfig, sessionConfig config.SessionConfig, cache Cache) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		session, err := loadSession(cache, ctx.Param("id"))
+		if err != nil || identity.NormalizeUsername(session.Username) != identity.NormalizeUsername(ctx.GetString("username")) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+
+		if session.ID == ctx.GetString("session_id") && session.Type == cookieSession {
+			clearSessionCookie(ctx, sessionConfig)
+		}
+		if err := revokeSession(cache, tokenConfig, session); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "could not revoke session"})
+			return
+		}
+
+		ctx.JSON(http.StatusOK, gin.H{"message": "session revoked"})
+	}
+}
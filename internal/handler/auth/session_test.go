@@ -0,0 +1,360 @@
+package auth_test
+
+import (
+	"Auth/config"
+	"Auth/internal/handler/auth"
+	"Auth/internal/model"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+var sessionCfg = config.SessionConfig{
+	Enabled:                true,
+	CookieName:             "auth_session",
+	Secure:                 true,
+	SameSite:               "strict",
+	IdleTimeoutMinutes:     30,
+	AbsoluteTimeoutMinutes: 720,
+	CSRF: config.CSRFConfig{
+		CookieName:     "csrf_token",
+		HeaderName:     "X-CSRF-Token",
+		TrustedOrigins: []string{"https://app.example.com"},
+	},
+}
+
+func newSessionRouter(t *testing.T, sessionConfig config.SessionConfig) (*gin.Engine, *MockUserRepository, map[string]string) {
+	gin.SetMode(gin.TestMode)
+	cache, store := newInMemoryCache()
+	keys := newTestKeyRing(t, testSecret)
+	repo := &MockUserRepository{User: &model.User{Model: gorm.Model{ID: 7}, Username: "alice", Password: hashPassword("secret")}}
+	repo.IncrementTokenVersionFunc = func(string) error {
+		repo.User.TokenVersion++
+		return nil
+	}
+
+	authenticated := auth.AuthHandler(keys, cache, repo, nil, nil, sessionConfig)
+	r := gin.New()
+	r.POST("/login", auth.LoginHandler(repo, refreshTokenCfg, lockoutCfg, emailCfg, sessionConfig, keys, cache))
+	r.POST("/logout", authenticated, auth.LogoutHandler(refreshTokenCfg, sessionConfig, cache))
+	r.POST("/logout/all", authenticated, auth.LogoutAllHandler(repo, cache))
+	r.PUT("/password", authenticated, auth.ChangePasswordHandler(repo, nil, cache))
+	r.POST("/token/refresh", auth.RefreshTokenHandler(repo, refreshTokenCfg, keys, cache))
+	r.GET("/auth", authenticated, auth.ForwardAuthHandler(defaultForwardAuthCfg))
+	r.GET("/sessions", authenticated, auth.SessionListHandler(sessionConfig, cache))
+	r.DELETE("/sessions/:id", authenticated, auth.SessionRevokeHandler(refreshTokenCfg, sessionConfig, cache))
+	return r, repo, store
+}
+
+// sessionLogin logs in like a browser and returns the session cookie and the
+// csrf token scripts read from the csrf cookie.
+func sessionLogin(t *testing.T, r *gin.Engine) (*http.Cookie, string) {
+	t.Helper()
+	resp := postJSON(r, "/login", auth.AuthInput{Username: "alice", Password: "secret", Session: true})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.NotContains(t, resp.Body.String(), "refresh_token")
+
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range resp.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Len(t, cookies, 2)
+	require.NotNil(t, cookies[sessionCfg.CookieName])
+	require.NotNil(t, cookies[sessionCfg.CSRF.CookieName])
+	return cookies[sessionCfg.CookieName], cookies[sessionCfg.CSRF.CookieName].Value
+}
+
+func withCookie(r *gin.Engine, method, target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	return withCSRF(r, method, target, cookie, "")
+}
+
+func withCSRF(r *gin.Engine, method, target string, cookie *http.Cookie, csrfToken string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	if csrfToken != "" {
+		req.Header.Set("X-CSRF-Token", csrfToken)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+// ageSession moves the timestamps of the only stored session into the past.
+func ageSession(t *testing.T, store map[string]string, created, lastSeen time.Duration) {
+	t.Helper()
+	for key, val := range store {
+		if !strings.HasPrefix(key, "session:") {
+			continue
+		}
+		var session map[string]any
+		require.NoError(t, json.Unmarshal([]byte(val), &session))
+		session["created_at"] = time.Now().Add(-created)
+		session["last_seen_at"] = time.Now().Add(-lastSeen)
+		updated, _ := json.Marshal(session)
+		store[key] = string(updated)
+		return
+	}
+	t.Fatal("no session stored")
+}
+
+func TestSession_CookieAuthenticates(t *testing.T) {
+	r, _, store := newSessionRouter(t, sessionCfg)
+
+	cookie, _ := sessionLogin(t, r)
+	assert.Equal(t, "auth_session", cookie.Name)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+	assert.Equal(t, 720*60, cookie.MaxAge)
+	_, stored := store["session:"+cookie.Value]
+	assert.False(t, stored, "the cookie value must not be stored")
+
+	resp := withCookie(r, http.MethodGet, "/auth", cookie)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "alice", resp.Header().Get(defaultForwardAuthCfg.UserHeader))
+	assert.Equal(t, "7", resp.Header().Get(defaultForwardAuthCfg.UserIDHeader))
+}
+
+func TestSession_RecordsClient(t *testing.T) {
+	r, _, store := newSessionRouter(t, sessionCfg)
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"alice","password":"secret","session":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test-browser/1.0")
+	req.RemoteAddr = "203.0.113.9:4711"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	for key, val := range store {
+		if strings.HasPrefix(key, "session:") {
+			assert.Contains(t, val, `"username":"alice"`)
+			assert.Contains(t, val, `"ip":"203.0.113.9"`)
+			assert.Contains(t, val, `"user_agent":"test-browser/1.0"`)
+			return
+		}
+	}
+	t.Fatal("no session stored")
+}
+
+func TestSession_IdleTimeoutSlides(t *testing.T) {
+	r, _, store := newSessionRouter(t, sessionCfg)
+	cookie, _ := sessionLogin(t, r)
+
+	ageSession(t, store, 2*time.Hour, 20*time.Minute)
+	require.Equal(t, http.StatusOK, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+
+	// the request above counts as activity
+	ageSession(t, store, 2*time.Hour, 20*time.Minute)
+	require.Equal(t, http.StatusOK, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+
+	ageSession(t, store, 2*time.Hour, 31*time.Minute)
+	resp := withCookie(r, http.MethodGet, "/auth", cookie)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "session expired")
+	assert.Equal(t, http.StatusUnauthorized, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+}
+
+func TestSession_AbsoluteTimeout(t *testing.T) {
+	r, _, store := newSessionRouter(t, sessionCfg)
+	cookie, _ := sessionLogin(t, r)
+
+	ageSession(t, store, 721*time.Minute, time.Minute)
+	resp := withCookie(r, http.MethodGet, "/auth", cookie)
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "session expired")
+}
+
+func TestSession_Logout(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, csrfToken := sessionLogin(t, r)
+
+	resp := withCSRF(r, http.MethodPost, "/logout", cookie, csrfToken)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	cleared := resp.Result().Cookies()
+	require.Len(t, cleared, 2)
+	for _, c := range cleared {
+		assert.Empty(t, c.Value)
+		assert.Negative(t, c.MaxAge)
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+}
+
+func TestSession_LogoutAllEndsSessions(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, csrfToken := sessionLogin(t, r)
+	other, _ := sessionLogin(t, r)
+
+	require.Equal(t, http.StatusOK, withCSRF(r, http.MethodPost, "/logout/all", cookie, csrfToken).Code)
+
+	assert.Equal(t, http.StatusUnauthorized, withCookie(r, http.MethodGet, "/auth", other).Code)
+}
+
+func TestSession_Disabled(t *testing.T) {
+	r, _, _ := newSessionRouter(t, config.SessionConfig{CookieName: "auth_session"})
+
+	resp := postJSON(r, "/login", auth.AuthInput{Username: "alice", Password: "secret", Session: true})
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = withCookie(r, http.MethodGet, "/auth", &http.Cookie{Name: "auth_session", Value: "anything"})
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "authorization header required")
+}
+
+func TestSession_BearerTokenStillWorks(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+
+	pair := login(t, r, "alice", "secret")
+	assert.Equal(t, http.StatusOK, getAuth(r, pair["token"].(string)).Code)
+}
+
+type listedSession struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Current   bool   `json:"current"`
+}
+
+func listSessions(t *testing.T, r *gin.Engine, token string) []listedSession {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var out struct {
+		Sessions []listedSession `json:"sessions"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	return out.Sessions
+}
+
+func loginFrom(t *testing.T, r *gin.Engine, userAgent string) map[string]any {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"alice","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &out))
+	return out
+}
+
+func deleteSession(r *gin.Engine, token, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodDelete, "/sessions/"+id, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestSessionListHandler(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	laptop := loginFrom(t, r, "laptop")
+	loginFrom(t, r, "phone")
+	sessionLogin(t, r)
+
+	sessions := listSessions(t, r, laptop["token"].(string))
+	require.Len(t, sessions, 3)
+
+	byAgent := map[string]listedSession{}
+	for _, session := range sessions {
+		byAgent[session.UserAgent] = session
+	}
+	assert.True(t, byAgent["laptop"].Current)
+	assert.Equal(t, "token", byAgent["laptop"].Type)
+	assert.False(t, byAgent["phone"].Current)
+	assert.Equal(t, "cookie", byAgent[""].Type)
+}
+
+func TestSessionRevokeHandler_RevokesTokensImmediately(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	laptop := loginFrom(t, r, "laptop")
+	phone := loginFrom(t, r, "phone")
+
+	// refreshed tokens stay in their session
+	resp := refresh(r, phone["refresh_token"].(string))
+	require.Equal(t, http.StatusOK, resp.Code)
+	var refreshed map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &refreshed))
+
+	var phoneID string
+	for _, session := range listSessions(t, r, laptop["token"].(string)) {
+		if session.UserAgent == "phone" {
+			phoneID = session.ID
+		}
+	}
+	require.NotEmpty(t, phoneID)
+
+	resp = deleteSession(r, laptop["token"].(string), phoneID)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = getAuth(r, refreshed["token"].(string))
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+	assert.Contains(t, resp.Body.String(), "session has been revoked")
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, phone["token"].(string)).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(r, refreshed["refresh_token"].(string)).Code)
+
+	assert.Equal(t, http.StatusOK, getAuth(r, laptop["token"].(string)).Code)
+	assert.Len(t, listSessions(t, r, laptop["token"].(string)), 1)
+}
+
+func TestSessionRevokeHandler_CookieSession(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	laptop := loginFrom(t, r, "laptop")
+	cookie, _ := sessionLogin(t, r)
+
+	resp := deleteSession(r, laptop["token"].(string), hashCookie(cookie))
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+}
+
+func TestSessionRevokeHandler_OtherUsersSession(t *testing.T) {
+	r, repo, _ := newSessionRouter(t, sessionCfg)
+	cookie, _ := sessionLogin(t, r)
+
+	repo.User = &model.User{Username: "mallory", Password: hashPassword("secret")}
+	mallory := login(t, r, "mallory", "secret")
+
+	resp := deleteSession(r, mallory["token"].(string), hashCookie(cookie))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+	assert.Equal(t, http.StatusNotFound, deleteSession(r, mallory["token"].(string), "unknown").Code)
+}
+
+func hashCookie(cookie *http.Cookie) string {
+	sum := sha256.Sum256([]byte(cookie.Value))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestSession_PasswordChangeEndsSessions(t *testing.T) {
+	r, _, _ := newSessionRouter(t, sessionCfg)
+	cookie, _ := sessionLogin(t, r)
+	laptop := loginFrom(t, r, "laptop")["token"].(string)
+	loginFrom(t, r, "phone")
+
+	resp := putPassword(r, laptop, auth.ChangePasswordInput{CurrentPassword: "secret", NewPassword: "new secret"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, withCookie(r, http.MethodGet, "/auth", cookie).Code)
+
+	token := login(t, r, "alice", "new secret")["token"].(string)
+	sessions := listSessions(t, r, token)
+	require.Len(t, sessions, 1)
+	assert.True(t, sessions[0].Current)
+}
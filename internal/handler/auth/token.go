@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"Auth/config"
+	"Auth/internal/model"
+	"Auth/internal/signing"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type tokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// tokenGrant records the OAuth client tokens were issued to and the scopes
+// the user granted it. Tokens from /login have none, but belong to a
+// session instead.
+type tokenGrant struct {
+	ClientID  string   `json:"client_id,omitempty"`
+	Scopes    []string `json:"scopes,omitempty"`
+	SessionID string   `json:"session_id,omitempty"`
+}
+
+func issueTokenPair(
+	cache Cache,
+	tokenConfig config.JWTConfig,
+	keys *signing.KeyRing,
+	user *model.User,
+	family string,
+	grant tokenGrant,
+) (*tokenPair, error) {
+	accessToken, err := generateAccessToken(tokenConfig, keys, user, grant)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := issueRefreshToken(cache, user, family, grant, refreshTokenTTL(tokenConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokenConfig.ExpirationMinutes * 60,
+	}, nil
+}
+
+// mfaTokenTTL is the time the user has to enter the second factor after the
+// password was accepted.
+const mfaTokenTTL = 5 * time.Minute
+
+const mfaTokenPurpose = "mfa"
+
+func generateAccessToken(tokenConfig config.JWTConfig, keys *signing.KeyRing, user *model.User, grant tokenGrant) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"username":    user.Username,
+		"uid":         user.ID,
+		"roles":       user.RoleNames(),
+		"permissions": user.Permissions(),
+		"ver":         user.TokenVersion,
+		"jti":         jti,
+		"iat":         now.Unix(),
+		"exp":         now.Add(time.Duration(tokenConfig.ExpirationMinutes) * time.Minute).Unix(),
+	}
+	if grant.ClientID != "" {
+		claims["client_id"] = grant.ClientID
+		claims["scope"] = strings.Join(grant.Scopes, " ")
+	}
+	if grant.SessionID != "" {
+		claims["sid"] = grant.SessionID
+	}
+
+	return keys.Sign(claims)
+}
+
+// generateClientToken issues an access token to a client acting on its own
+// behalf. It has no username, the client is the subject and its scopes take
+// the place of a user's permissions.
+func generateClientToken(tokenConfig config.JWTConfig, keys *signing.KeyRing, client *model.Client, scopes []string) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":       client.ClientID,
+		"client_id": client.ClientID,
+		"scope":     strings.Join(scopes, " "),
+		"jti":       jti,
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Duration(tokenConfig.ExpirationMinutes) * time.Minute).Unix(),
+	}
+
+	return keys.Sign(claims)
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// generateMFAToken issues the short-lived token proving that the password
+// step of the login succeeded. It carries a purpose claim and no username,
+// so AuthHandler never accepts it as an access token.
+func generateMFAToken(keys *signing.KeyRing, user *model.User) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return keys.Sign(jwt.MapClaims{
+		"sub":     user.Username,
+		"uid":     user.ID,
+		"purpose": mfaTokenPurpose,
+		"ver":     user.TokenVersion,
+		"jti":     jti,
+		"iat":     now.Unix(),
+		"exp":     now.Add(mfaTokenTTL).Unix(),
+	})
+}
+
+func parseMFAToken(keys *signing.KeyRing, tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != mfaTokenPurpose {
+		return nil, errors.New("not an mfa token")
+	}
+
+	return claims, nil
+}
+
+// newOpaqueToken returns a random token for flows where the server keeps the
+// state, such as refresh or password reset tokens.
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used to key opaque tokens in the cache, so that a dump of the
+// cache does not contain usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
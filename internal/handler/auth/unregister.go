@@ -1,20 +1,12 @@
 package auth
 
 import (
-	"Auth/config"
-	"fmt"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UnregisterHandler(
-	repo UserDeleteRepository,
-	cache Cache,
-	tokenConfig config.JWTConfig,
-) gin.HandlerFunc {
+func UnregisterHandler(repo UserDeleteRepository, cache Cache) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		username, exists := ctx.Get("username")
 
@@ -29,8 +21,7 @@ func UnregisterHandler(
 			return
 		}
 
-		redisKey := fmt.Sprintf("user:%s", usernameStr)
-		if err := cache.Delete(redisKey); err != nil {
+		if err := forgetUser(cache, usernameStr); err != nil {
 			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "user not found"})
 			return
 		}
@@ -40,11 +31,7 @@ func UnregisterHandler(
 			return
 		}
 
-		authHeader := ctx.GetHeader("Authorization")
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		blacklistKey := fmt.Sprintf("jwt_blacklist:%s", tokenString)
-		expiration := time.Duration(tokenConfig.ExpirationMinutes) * time.Minute
-		_ = cache.Set(blacklistKey, "invalid", expiration)
+		_ = revokeCurrentToken(ctx, cache)
 
 		ctx.JSON(http.StatusOK, gin.H{"message": "user deleted"})
 	}
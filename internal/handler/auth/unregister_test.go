@@ -1,8 +1,8 @@
 package auth_test
 
 import (
-	"Auth/config"
 	"Auth/internal/handler/auth"
+	"Auth/internal/model"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +11,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestUnregisterHandler(t *testing.T) {
@@ -20,7 +22,7 @@ func TestUnregisterHandler(t *testing.T) {
 		name               string
 		username           any
 		usernameExists     bool
-		authHeader         string
+		tokenID            string
 		setupCache         func() *MockCacheRepository
 		setupRepo          func() *MockUserRepository
 		expectedStatusCode int
@@ -30,7 +32,7 @@ func TestUnregisterHandler(t *testing.T) {
 			name:               "missing username in context",
 			username:           nil,
 			usernameExists:     false,
-			authHeader:         "",
+			tokenID:            "",
 			setupCache:         func() *MockCacheRepository { return &MockCacheRepository{} },
 			setupRepo:          func() *MockUserRepository { return &MockUserRepository{} },
 			expectedStatusCode: http.StatusUnauthorized,
@@ -40,7 +42,7 @@ func TestUnregisterHandler(t *testing.T) {
 			name:               "invalid username type",
 			username:           123,
 			usernameExists:     true,
-			authHeader:         "",
+			tokenID:            "",
 			setupCache:         func() *MockCacheRepository { return &MockCacheRepository{} },
 			setupRepo:          func() *MockUserRepository { return &MockUserRepository{} },
 			expectedStatusCode: http.StatusUnauthorized,
@@ -50,7 +52,7 @@ func TestUnregisterHandler(t *testing.T) {
 			name:           "cache delete error",
 			username:       "user1",
 			usernameExists: true,
-			authHeader:     "Bearer token123",
+			tokenID:        "jti-123",
 			setupCache: func() *MockCacheRepository {
 				return &MockCacheRepository{
 					DeleteFunc: func(key string) error {
@@ -69,7 +71,7 @@ func TestUnregisterHandler(t *testing.T) {
 			name:           "repo delete error",
 			username:       "user1",
 			usernameExists: true,
-			authHeader:     "Bearer token123",
+			tokenID:        "jti-123",
 			setupCache: func() *MockCacheRepository {
 				return &MockCacheRepository{
 					DeleteFunc: func(key string) error {
@@ -93,7 +95,7 @@ func TestUnregisterHandler(t *testing.T) {
 			name:           "successful delete without token",
 			username:       "user1",
 			usernameExists: true,
-			authHeader:     "",
+			tokenID:        "",
 			setupCache: func() *MockCacheRepository {
 				return &MockCacheRepository{
 					DeleteFunc: func(key string) error {
@@ -117,7 +119,7 @@ func TestUnregisterHandler(t *testing.T) {
 			name:           "successful delete with token",
 			username:       "user1",
 			usernameExists: true,
-			authHeader:     "Bearer token123",
+			tokenID:        "jti-123",
 			setupCache: func() *MockCacheRepository {
 				return &MockCacheRepository{
 					DeleteFunc: func(key string) error {
@@ -127,9 +129,9 @@ func TestUnregisterHandler(t *testing.T) {
 						return errors.New("unexpected key")
 					},
 					SetFunc: func(key string, value any, expiration time.Duration) error {
-						assert.Equal(t, "jwt_blacklist:token123", key)
+						assert.Equal(t, "jwt_blacklist:jti-123", key)
 						assert.Equal(t, "invalid", value)
-						assert.Equal(t, 15*time.Minute, expiration)
+						assert.InDelta(t, float64(10*time.Minute), float64(expiration), float64(5*time.Second))
 						return nil
 					},
 				}
@@ -163,13 +165,12 @@ func TestUnregisterHandler(t *testing.T) {
 				ctx.Set("username", tt.username)
 			}
 
-			if tt.authHeader != "" {
-				ctx.Request.Header.Set("Authorization", tt.authHeader)
+			if tt.tokenID != "" {
+				ctx.Set("token_id", tt.tokenID)
+				ctx.Set("token_expires_at", time.Now().Add(10*time.Minute))
 			}
 
-			handler := auth.UnregisterHandler(repo, cache, config.JWTConfig{
-				ExpirationMinutes: 15,
-			})
+			handler := auth.UnregisterHandler(repo, cache)
 
 			handler(ctx)
 
@@ -178,3 +179,33 @@ func TestUnregisterHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestUnregisterHandler_OldTokensDoNotCarryOverToANewAccount(t *testing.T) {
+	cache, _ := newInMemoryCache()
+	repo := &MockUserRepository{User: &model.User{Model: gorm.Model{ID: 1}, Username: "alice", Password: hashPassword("secret")}}
+	repo.DeleteFunc = func(username string) error {
+		repo.User = nil
+		return nil
+	}
+	r := newLogoutAllRouter(t, repo, cache)
+	keys := newTestKeyRing(t, testSecret)
+	r.DELETE("/unregister", auth.AuthHandler(keys, cache, repo, nil, nil, sessionCfg), auth.UnregisterHandler(repo, cache))
+
+	first := login(t, r, "alice", "secret")
+	old := login(t, r, "alice", "secret")
+
+	req := httptest.NewRequest(http.MethodDelete, "/unregister", nil)
+	req.Header.Set("Authorization", "Bearer "+first["token"].(string))
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	// someone else registers the freed username, starting over at version 0
+	repo.User = &model.User{Model: gorm.Model{ID: 2}, Username: "alice", Password: hashPassword("other")}
+
+	assert.Equal(t, http.StatusUnauthorized, getAuth(r, old["token"].(string)).Code)
+	assert.Equal(t, http.StatusUnauthorized, refresh(r, old["refresh_token"].(string)).Code)
+
+	fresh := login(t, r, "alice", "other")
+	assert.Equal(t, http.StatusOK, getAuth(r, fresh["token"].(string)).Code)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"Auth/internal/identity"
+	"Auth/internal/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const userCacheTTL = 5 * time.Minute
+
+// userCacheKey normalizes the username, so that lookups by the submitted and
+// the stored spelling share one cached copy forgetUser can drop.
+func userCacheKey(username string) string {
+	return fmt.Sprintf("user:%s", identity.NormalizeUsername(username))
+}
+
+// findUser returns the user from the cache, falling back to the repository
+// and caching the result. Anything that changes the user record must drop
+// the cached copy with forgetUser.
+func findUser(ctx context.Context, repo UserFindByUsernameRepository, cache Cache, username string) (*model.User, error) {
+	if val, err := cache.Get(userCacheKey(username)); err == nil && val != "" {
+		var user model.User
+		if err := json.Unmarshal([]byte(val), &user); err == nil {
+			return &user, nil
+		}
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	user, err := repo.FindByUsername(reqCtx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = cacheUser(cache, user)
+	return user, nil
+}
+
+// cacheUser stores the user for findUser, for as long as any cached copy
+// lives so that changes to the record show within userCacheTTL. Cached
+// users lack the MFA secrets, load the user from the repository to verify
+// or update them.
+func cacheUser(cache Cache, user *model.User) error {
+	userJSON, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return cache.Set(userCacheKey(user.Username), userJSON, userCacheTTL)
+}
+
+func forgetUser(cache Cache, username string) error {
+	return cache.Delete(userCacheKey(username))
+}
+
+// sameAccount reports whether the user is still the account a token or
+// record was issued to. Deleting an account frees its username, the ID tells
+// a new account of the same name apart and the token version a changed one.
+func sameAccount(user *model.User, userID uint, tokenVersion int) bool {
+	return user.ID == userID && user.TokenVersion == tokenVersion
+}
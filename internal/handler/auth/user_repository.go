@@ -16,3 +16,27 @@ type UserFindByUsernameRepository interface {
 type UserDeleteRepository interface {
 	Delete(username string) error
 }
+
+type UserTokenVersionRepository interface {
+	IncrementTokenVersion(username string) error
+}
+
+type UserUpdateRepository interface {
+	Update(user *model.User) error
+}
+
+type UserPasswordRepository interface {
+	UserFindByUsernameRepository
+	UserUpdateRepository
+}
+
+// UserAdminRepository is what the admin API needs to manage users.
+type UserAdminRepository interface {
+	UserPasswordRepository
+	UserDeleteRepository
+	UserTokenVersionRepository
+	FindByID(ctx context.Context, id uint) (*model.User, error)
+	List(ctx context.Context, filter model.UserFilter) ([]model.User, int64, error)
+	FindRoles(ctx context.Context, names []string) ([]model.Role, error)
+	SetRoles(user *model.User, roles []model.Role) error
+}
@@ -0,0 +1,78 @@
+package identity
+
+import (
+	"Auth/config"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrEmailInvalid    = errors.New("invalid email address")
+	ErrUsernameInvalid = errors.New("username contains characters that are not allowed")
+)
+
+// NormalizeUsername returns the form usernames are stored and looked up in:
+// NFKC normalized and case folded, so that "Alice", "alice " and "ａｌｉｃｅ"
+// are the same account.
+func NormalizeUsername(username string) string {
+	return foldCase(strings.TrimSpace(username))
+}
+
+// NormalizeEmail validates a bare email address and returns it normalized
+// like usernames. Case folding the local part is not strictly correct, but
+// no provider in use treats it as case sensitive.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return "", ErrEmailInvalid
+	}
+	return foldCase(email), nil
+}
+
+func foldCase(value string) string {
+	return norm.NFKC.String(cases.Fold().String(norm.NFKC.String(value)))
+}
+
+// UsernamePolicy decides which usernames can be registered.
+type UsernamePolicy struct {
+	MinLength int
+	MaxLength int
+	Allowed   *regexp.Regexp
+}
+
+// NewUsernamePolicy builds the policy configured by the USERNAME_* settings.
+func NewUsernamePolicy(cfg config.UsernameConfig) (*UsernamePolicy, error) {
+	if cfg.MinLength < 1 || cfg.MaxLength < cfg.MinLength {
+		return nil, fmt.Errorf("invalid username length limits %d-%d", cfg.MinLength, cfg.MaxLength)
+	}
+
+	allowed, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid username pattern: %w", err)
+	}
+
+	return &UsernamePolicy{MinLength: cfg.MinLength, MaxLength: cfg.MaxLength, Allowed: allowed}, nil
+}
+
+// Check normalizes the username and returns it when the normalized form is
+// acceptable. The pattern applies to the normalized form.
+func (p *UsernamePolicy) Check(username string) (string, error) {
+	username = NormalizeUsername(username)
+
+	if length := utf8.RuneCountInString(username); length < p.MinLength || length > p.MaxLength {
+		return "", fmt.Errorf("username must be between %d and %d characters long", p.MinLength, p.MaxLength)
+	}
+	if p.Allowed != nil && !p.Allowed.MatchString(username) {
+		return "", ErrUsernameInvalid
+	}
+
+	return username, nil
+}
@@ -0,0 +1,60 @@
+package identity_test
+
+import (
+	"Auth/config"
+	"Auth/internal/identity"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testUsernameCfg = config.UsernameConfig{MinLength: 3, MaxLength: 16, Pattern: `^[\p{L}\p{N}][\p{L}\p{N}._-]*$`}
+
+func TestNormalizeUsername(t *testing.T) {
+	tests := map[string]string{
+		"Alice":          "alice",
+		" alice ":        "alice",
+		"ＡＬＩＣＥ":          "alice",
+		"Straße":         "strasse",
+		"ǅemal":          "džemal",
+		"émile":         "émile",
+		"already.normal": "already.normal",
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, identity.NormalizeUsername(input), input)
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	email, err := identity.NormalizeEmail(" Alice@Example.COM ")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", email)
+
+	for _, invalid := range []string{"alice", "Alice <alice@example.com>", "alice@", "@example.com"} {
+		_, err := identity.NormalizeEmail(invalid)
+		assert.ErrorIs(t, err, identity.ErrEmailInvalid, invalid)
+	}
+}
+
+func TestUsernamePolicy_Check(t *testing.T) {
+	policy, err := identity.NewUsernamePolicy(testUsernameCfg)
+	require.NoError(t, err)
+
+	username, err := policy.Check("Zoë.Smith")
+	require.NoError(t, err)
+	assert.Equal(t, "zoë.smith", username)
+
+	for _, invalid := range []string{"al", "a-very-long-username", "user:alice", "-alice", "alice smith", "alice@example.com"} {
+		_, err := policy.Check(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestNewUsernamePolicy_ValidatesConfig(t *testing.T) {
+	_, err := identity.NewUsernamePolicy(config.UsernameConfig{MinLength: 0, MaxLength: 10, Pattern: ".*"})
+	assert.Error(t, err)
+
+	_, err = identity.NewUsernamePolicy(config.UsernameConfig{MinLength: 3, MaxLength: 10, Pattern: "["})
+	assert.Error(t, err)
+}
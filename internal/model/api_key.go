@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey is a long-lived credential a user creates for CI jobs and
+// integrations. Only the sha256 hash of the key is stored, the prefix is
+// kept in the clear so that users can tell their keys apart.
+type APIKey struct {
+	gorm.Model
+	UserID     uint       `json:"-" gorm:"not null;index"`
+	User       *User      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	Hash       string     `json:"-" gorm:"uniqueIndex;not null"`
+	Scopes     []string   `json:"scopes" gorm:"type:jsonb;serializer:json"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// TokenVersion is the user's token version when the key was created,
+	// keys end with the user's tokens when the version is bumped.
+	TokenVersion int `json:"-" gorm:"not null;default:0"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
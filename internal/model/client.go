@@ -0,0 +1,83 @@
+package model
+
+import (
+	"slices"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+)
+
+// Client is an application allowed to obtain tokens from the OAuth 2.0
+// endpoints. Public clients act on behalf of users through the authorization
+// code flow, confidential ones hold a secret and may get tokens of their own
+// with the client credentials grant, limited to their scopes.
+type Client struct {
+	gorm.Model
+	ClientID     string   `json:"client_id" gorm:"unique;not null"`
+	Name         string   `json:"name" gorm:"not null"`
+	SecretHash   string   `json:"-"`
+	RedirectURIs []string `json:"redirect_uris" gorm:"type:jsonb;serializer:json"`
+	GrantTypes   []string `json:"grant_types" gorm:"type:jsonb;serializer:json"`
+	Scopes       []string `json:"scopes" gorm:"type:jsonb;serializer:json"`
+}
+
+func (Client) TableName() string {
+	return "clients"
+}
+
+// HasRedirectURI reports whether the uri is registered for the client. URIs
+// are compared as plain strings, as required for authorization requests.
+func (c *Client) HasRedirectURI(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// AllowsGrant reports whether the client may use the grant type. Clients
+// registered before grant types were stored only use authorization codes.
+func (c *Client) AllowsGrant(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return grantType == GrantAuthorizationCode
+	}
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+func (c *Client) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+func (c *Client) SetSecret(secret string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	c.SecretHash = string(hash)
+	return nil
+}
+
+func (c *Client) CheckSecret(secret string) bool {
+	return c.SecretHash != "" && bcrypt.CompareHashAndPassword([]byte(c.SecretHash), []byte(secret)) == nil
+}
+
+// GrantScopes returns the scopes a token for the client gets. Without a
+// request the client gets all of its scopes, asking for one it was not
+// registered with fails.
+func (c *Client) GrantScopes(requested []string) ([]string, bool) {
+	if len(requested) == 0 {
+		return slices.Clone(c.Scopes), true
+	}
+
+	granted := []string{}
+	for _, scope := range requested {
+		if !slices.Contains(c.Scopes, scope) {
+			return nil, false
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return granted, true
+}
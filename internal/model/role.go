@@ -0,0 +1,13 @@
+package model
+
+import "gorm.io/gorm"
+
+type Role struct {
+	gorm.Model
+	Name        string   `json:"name" gorm:"unique;not null"`
+	Permissions []string `json:"permissions" gorm:"type:jsonb;serializer:json"`
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
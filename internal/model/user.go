@@ -1,20 +1,97 @@
 package model
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sort"
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// User is stored with a normalized username and email, see the identity
+// package; the unique indexes on their lowercase forms also keep apart rows
+// created before usernames were normalized.
 type User struct {
 	gorm.Model
-	Username string `json:"username" gorm:"unique;not null"`
-	Password string `json:"password" gorm:"not null"`
+	Username     string `json:"username" gorm:"unique;not null;index:idx_users_username_lower,unique,expression:lower(username)"`
+	Password     string `json:"password" gorm:"not null"`
+	TokenVersion int    `json:"token_version" gorm:"not null;default:0"`
+	// Only active accounts can log in and use their tokens. Admins set the
+	// status with a reason, StatusChangedAt is when it was last changed.
+	Status          UserStatus `json:"status" gorm:"type:varchar(32);not null;default:'active';index"`
+	StatusReason    string     `json:"status_reason" gorm:"not null;default:''"`
+	StatusChangedAt *time.Time `json:"status_changed_at"`
+
+	Email           string     `json:"email" gorm:"not null;default:'';index:idx_users_email_lower,unique,expression:lower(email),where:email <> ''"`
+	EmailVerified   bool       `json:"email_verified" gorm:"not null;default:false"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// The MFA secrets never leave the database, not even for the user
+	// cache; MFA is verified against the repository.
+	TOTPSecret    string   `json:"-"`
+	TOTPEnabled   bool     `json:"totp_enabled" gorm:"not null;default:false"`
+	RecoveryCodes []string `json:"-" gorm:"type:jsonb;serializer:json"`
+
+	Roles []Role `json:"roles" gorm:"many2many:user_roles;"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
+type UserStatus string
+
+const (
+	UserStatusActive              UserStatus = "active"
+	UserStatusDisabled            UserStatus = "disabled"
+	UserStatusLocked              UserStatus = "locked"
+	UserStatusPendingVerification UserStatus = "pending_verification"
+)
+
+func (s UserStatus) Valid() bool {
+	switch s {
+	case UserStatusActive, UserStatusDisabled, UserStatusLocked, UserStatusPendingVerification:
+		return true
+	}
+	return false
+}
+
+// AccountStatus is the status of the user, users without one are active.
+func (u *User) AccountStatus() UserStatus {
+	if u.Status == "" {
+		return UserStatusActive
+	}
+	return u.Status
+}
+
+func (u *User) Active() bool {
+	return u.AccountStatus() == UserStatusActive
+}
+
+// SetStatus changes the status and remembers why and when. The caller has to
+// persist the user afterwards.
+func (u *User) SetStatus(status UserStatus, reason string) {
+	now := time.Now()
+	u.Status = status
+	u.StatusReason = reason
+	u.StatusChangedAt = &now
+}
+
+// UserFilter selects the users an admin lists. Username and Email match
+// parts of the normalized values, Role a role the user has, Status the
+// account status when set.
+type UserFilter struct {
+	Username string
+	Email    string
+	Role     string
+	Status   UserStatus
+	Offset   int
+	Limit    int
+}
+
 func (u *User) SetPassword() error {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -25,5 +102,57 @@ func (u *User) SetPassword() error {
 }
 
 func (u *User) CheckPassword(password string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
+
+func (u *User) RoleNames() []string {
+	names := make([]string, 0, len(u.Roles))
+	for _, role := range u.Roles {
+		names = append(names, role.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Permissions returns the union of the permissions granted by the user's roles.
+func (u *User) Permissions() []string {
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, role := range u.Roles {
+		for _, permission := range role.Permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// SetRecoveryCodes stores the hashes of the given MFA recovery codes,
+// replacing any previous ones.
+func (u *User) SetRecoveryCodes(codes []string) {
+	u.RecoveryCodes = make([]string, 0, len(codes))
+	for _, code := range codes {
+		u.RecoveryCodes = append(u.RecoveryCodes, hashRecoveryCode(code))
+	}
+}
+
+// UseRecoveryCode removes the matching recovery code, so that every code
+// works only once. The caller has to persist the user afterwards.
+func (u *User) UseRecoveryCode(code string) bool {
+	hashed := hashRecoveryCode(code)
+	for i, stored := range u.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hashed)) == 1 {
+			u.RecoveryCodes = append(u.RecoveryCodes[:i], u.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,34 @@
+package notify_test
+
+import (
+	"Auth/internal/notify"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileNotifier_AppendsMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	notifier := notify.NewFileNotifier(path)
+
+	require.NoError(t, notifier.Notify(context.Background(), notify.Message{To: "alice", Subject: "first", Body: "one"}))
+	require.NoError(t, notifier.Notify(context.Background(), notify.Message{To: "bob", Subject: "second", Body: "two"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "bob", entry["to"])
+	assert.Equal(t, "second", entry["subject"])
+	assert.Equal(t, "two", entry["body"])
+}
@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"os"
+)
+
+// LogNotifier writes messages to the application log. Meant for local
+// development only, the log then contains secrets such as reset tokens.
+type LogNotifier struct {
+	logger *log.Logger
+}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{logger: log.New(os.Stdout, "[notify] ", log.LstdFlags)}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, msg Message) error {
+	n.logger.Printf("to=%q subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"Auth/config"
+	"context"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages such as password reset tokens to users. To is
+// the user's email address, or the username of users without one.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+func NewNotifier(cfg config.NotifierConfig) (Notifier, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLogNotifier(), nil
+	case "file":
+		if cfg.FilePath == "" {
+			return nil, fmt.Errorf("NOTIFIER_FILE is required for the file notifier")
+		}
+		return NewFileNotifier(cfg.FilePath), nil
+	case "smtp":
+		return NewSMTPNotifier(cfg.SMTP)
+	default:
+		return nil, fmt.Errorf("unknown notifier driver: %s", cfg.Driver)
+	}
+}
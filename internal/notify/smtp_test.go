@@ -0,0 +1,92 @@
+package notify_test
+
+import (
+	"Auth/config"
+	"Auth/internal/notify"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one message and returns its envelope and data.
+func fakeSMTPServer(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		lines := []string{}
+		_ = text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line + " x")[0]); command {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				lines = append(lines, line)
+				_ = text.PrintfLine("250 ok")
+			case "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				data, _ := text.ReadDotLines()
+				lines = append(lines, data...)
+				_ = text.PrintfLine("250 queued")
+			case "QUIT":
+				_ = text.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				_ = text.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSMTPNotifier_SendsMail(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	notifier, err := notify.NewSMTPNotifier(config.SMTPConfig{Addr: addr, From: "Auth <no-reply@example.com>"})
+	require.NoError(t, err)
+
+	err = notifier.Notify(context.Background(), notify.Message{
+		To:      "alice@example.com",
+		Subject: "Verify your email – Auth",
+		Body:    "first line\nsecond line",
+	})
+	require.NoError(t, err)
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<alice@example.com>")
+	assert.Contains(t, lines, "To: <alice@example.com>")
+	assert.Contains(t, lines, "Subject: =?utf-8?q?Verify_your_email_=E2=80=93_Auth?=")
+	assert.Contains(t, lines, "second line")
+}
+
+func TestSMTPNotifier_RejectsInvalidMessages(t *testing.T) {
+	notifier, err := notify.NewSMTPNotifier(config.SMTPConfig{Addr: "127.0.0.1:25", From: "no-reply@example.com"})
+	require.NoError(t, err)
+
+	assert.Error(t, notifier.Notify(context.Background(), notify.Message{To: "alice", Subject: "hi"}))
+	assert.Error(t, notifier.Notify(context.Background(), notify.Message{To: "alice@example.com", Subject: "hi\r\nBcc: eve@example.com"}))
+}
+
+func TestNewNotifier_SMTPNeedsAddress(t *testing.T) {
+	_, err := notify.NewNotifier(config.NotifierConfig{Driver: "smtp", SMTP: config.SMTPConfig{From: "no-reply@example.com"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+package passwords
+
+import (
+	"Auth/config"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxBytes is the length bcrypt hashes, anything after it is ignored.
+const bcryptMaxBytes = 72
+
+// Violation is a rule of the policy a password breaks.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Policy decides which passwords users may choose.
+type Policy struct {
+	MinLength        int
+	MaxBytes         int
+	MinClasses       int
+	DisallowUsername bool
+	Breached         *BreachedList
+}
+
+// New builds the policy configured by the PASSWORD_* settings.
+func New(cfg config.PasswordConfig) (*Policy, error) {
+	policy := &Policy{
+		MinLength:        cfg.MinLength,
+		MaxBytes:         cfg.MaxBytes,
+		MinClasses:       cfg.MinClasses,
+		DisallowUsername: cfg.DisallowUsername,
+	}
+	if policy.MaxBytes <= 0 || policy.MaxBytes > bcryptMaxBytes {
+		return nil, fmt.Errorf("maximum password length must be between 1 and %d bytes", bcryptMaxBytes)
+	}
+	if policy.MinLength > policy.MaxBytes {
+		return nil, fmt.Errorf("minimum password length %d exceeds the maximum of %d", policy.MinLength, policy.MaxBytes)
+	}
+	if policy.MinClasses < 0 || policy.MinClasses > 4 {
+		return nil, fmt.Errorf("required character classes must be between 0 and 4")
+	}
+
+	if cfg.BreachedList != "" {
+		breached, err := OpenBreachedList(cfg.BreachedList)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+
+	return policy, nil
+}
+
+// Check returns the violations of a password chosen by username, none when
+// the password is acceptable. The breached list is consulted only for
+// passwords passing every other rule.
+func (p *Policy) Check(password, username string) []Violation {
+	violations := []Violation{}
+
+	if length := utf8.RuneCountInString(password); length < max(p.MinLength, 1) {
+		violations = append(violations, Violation{
+			Rule:    "min_length",
+			Message: fmt.Sprintf("must be at least %d characters long", max(p.MinLength, 1)),
+		})
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, Violation{
+			Rule:    "max_length",
+			Message: fmt.Sprintf("must not be longer than %d bytes", p.MaxBytes),
+		})
+	}
+	if classes := characterClasses(password); classes < p.MinClasses {
+		violations = append(violations, Violation{
+			Rule: "character_classes",
+			Message: fmt.Sprintf(
+				"must use at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses,
+			),
+		})
+	}
+	if p.DisallowUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, Violation{
+			Rule:    "username",
+			Message: "must not contain the username",
+		})
+	}
+
+	if len(violations) == 0 && p.Breached != nil {
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			log.Printf("could not check breached passwords: %v", err)
+		}
+		if breached {
+			violations = append(violations, Violation{
+				Rule:    "breached",
+				Message: "appears in a known data breach, choose another one",
+			})
+		}
+	}
+
+	return violations
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
@@ -0,0 +1,76 @@
+package passwords_test
+
+import (
+	"Auth/config"
+	"Auth/internal/passwords"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rules(violations []passwords.Violation) []string {
+	names := []string{}
+	for _, violation := range violations {
+		names = append(names, violation.Rule)
+	}
+	return names
+}
+
+func TestPolicy_Check(t *testing.T) {
+	policy := &passwords.Policy{MinLength: 10, MaxBytes: 72, MinClasses: 3, DisallowUsername: true}
+
+	tests := []struct {
+		password string
+		rules    []string
+	}{
+		{"Correct-Horse-7", []string{}},
+		{"", []string{"min_length", "character_classes"}},
+		{"short-A1", []string{"min_length"}},
+		{"lowercase only here", []string{"character_classes"}},
+		{"Alice-in-Wonderland-1", []string{"username"}},
+		{"Zażółć-gęślą-1", []string{}},
+		{strings.Repeat("Ab1-", 19), []string{"max_length"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.rules, rules(policy.Check(tt.password, "alice")), tt.password)
+	}
+}
+
+func TestPolicy_EmptyPasswordIsNeverAccepted(t *testing.T) {
+	policy := &passwords.Policy{MaxBytes: 72}
+	assert.Equal(t, []string{"min_length"}, rules(policy.Check("", "alice")))
+}
+
+func TestNew_ValidatesLimits(t *testing.T) {
+	_, err := passwords.New(config.PasswordConfig{MinLength: 8, MaxBytes: 100})
+	assert.Error(t, err)
+
+	_, err = passwords.New(config.PasswordConfig{MinLength: 80, MaxBytes: 72})
+	assert.Error(t, err)
+
+	_, err = passwords.New(config.PasswordConfig{MinLength: 8, MaxBytes: 72, BreachedList: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
+
+func TestBreachedList(t *testing.T) {
+	dir := t.TempDir()
+	sum := sha1.Sum([]byte("Password-123"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	require.NoError(t, os.WriteFile(
+		filepath.Join(dir, hash[:5]+".txt"),
+		[]byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"+strings.ToLower(hash[5:])+":52254\r\n"),
+		0o600,
+	))
+
+	policy, err := passwords.New(config.PasswordConfig{MinLength: 8, MaxBytes: 72, BreachedList: dir})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"breached"}, rules(policy.Check("Password-123", "alice")))
+	assert.Empty(t, policy.Check("Password-124", "alice"))
+}
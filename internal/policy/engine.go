@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"Auth/config"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Engine evaluates requests against the current policy. The policy can be
+// reloaded from its file while requests are being evaluated.
+type Engine struct {
+	mu     sync.RWMutex
+	policy *Policy
+	source func() (*Policy, error)
+}
+
+func NewEngine(policy *Policy) *Engine {
+	return &Engine{policy: policy}
+}
+
+// Load builds an engine from POLICY_FILE. Without a file every request needs
+// a valid token and nothing else, which is how /auth behaved before policies.
+func Load(cfg config.PolicyConfig) (*Engine, error) {
+	source := func() (*Policy, error) {
+		if cfg.File == "" {
+			return &Policy{Default: Authenticated}, nil
+		}
+		data, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy file: %w", err)
+		}
+		return Parse(data)
+	}
+
+	policy, err := source()
+	if err != nil {
+		return nil, err
+	}
+
+	engine := NewEngine(policy)
+	engine.source = source
+	return engine, nil
+}
+
+// Reload re-reads the policy file. On error the current policy stays in
+// place.
+func (e *Engine) Reload() error {
+	if e.source == nil {
+		return errors.New("policy engine has no file to reload from")
+	}
+
+	policy, err := e.source()
+	if err != nil {
+		return err
+	}
+
+	e.Replace(policy)
+	return nil
+}
+
+func (e *Engine) Replace(policy *Policy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = policy
+}
+
+// Rules returns the number of rules of the current policy.
+func (e *Engine) Rules() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.policy.Rules)
+}
+
+func (e *Engine) Evaluate(req Request) Decision {
+	e.mu.RLock()
+	policy := e.policy
+	e.mu.RUnlock()
+	return policy.Evaluate(req)
+}
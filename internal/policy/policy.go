@@ -0,0 +1,227 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Access is what a rule grants to the requests it matches.
+type Access string
+
+const (
+	// Public requests pass without a token.
+	Public Access = "public"
+	// Authenticated requests need a valid token carrying the roles and
+	// permissions of the rule.
+	Authenticated Access = "authenticated"
+	// Deny rejects matching requests whoever makes them.
+	Deny Access = "deny"
+)
+
+// Rule matches requests by host, path and method. Empty fields match
+// anything. Host and path are globs in the syntax of path.Match, a "**" path
+// segment additionally matches any number of segments, e.g. "/api/**".
+//
+// With several roles any of them is enough, every permission is required.
+type Rule struct {
+	Name        string   `json:"name,omitempty"`
+	Host        string   `json:"host,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Methods     []string `json:"methods,omitempty"`
+	Access      Access   `json:"access,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Policy is an ordered list of rules, the first matching rule decides.
+// Requests no rule matches get the default access, which is Authenticated
+// unless set otherwise.
+type Policy struct {
+	Default Access `json:"default,omitempty"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Request is the part of an HTTP request a policy looks at. Path is the
+// request URI as sent, still percent-encoded.
+type Request struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+}
+
+// Decision is the outcome of evaluating a request. Rule names the matching
+// rule, it is empty when the default applied.
+type Decision struct {
+	Access      Access   `json:"access"`
+	Rule        string   `json:"rule,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Parse decodes and validates a policy document.
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("invalid policy: %w", err)
+	}
+
+	if policy.Default == "" {
+		policy.Default = Authenticated
+	}
+	if !validAccess(policy.Default) {
+		return nil, fmt.Errorf("invalid default access %q", policy.Default)
+	}
+
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if rule.Name == "" {
+			rule.Name = "#" + strconv.Itoa(i)
+		}
+		if rule.Access == "" {
+			rule.Access = Authenticated
+		}
+		if !validAccess(rule.Access) {
+			return nil, fmt.Errorf("rule %s: invalid access %q", rule.Name, rule.Access)
+		}
+		if rule.Access != Authenticated && (len(rule.Roles) > 0 || len(rule.Permissions) > 0) {
+			return nil, fmt.Errorf("rule %s: roles and permissions only apply to authenticated access", rule.Name)
+		}
+		if _, err := path.Match(rule.Host, ""); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid host pattern: %w", rule.Name, err)
+		}
+		if _, err := path.Match(rule.Path, ""); err != nil {
+			return nil, fmt.Errorf("rule %s: invalid path pattern: %w", rule.Name, err)
+		}
+		rule.Host = strings.ToLower(rule.Host)
+		for j, method := range rule.Methods {
+			rule.Methods[j] = strings.ToUpper(method)
+		}
+	}
+
+	return &policy, nil
+}
+
+// Evaluate returns the decision of the first rule matching the request.
+func (p *Policy) Evaluate(req Request) Decision {
+	host := normalizeHost(req.Host)
+	method := strings.ToUpper(req.Method)
+	requestPath, ok := normalizePath(req.Path)
+	if !ok {
+		return Decision{Access: Deny}
+	}
+
+	for _, rule := range p.Rules {
+		if rule.matches(method, host, requestPath) {
+			return Decision{
+				Access:      rule.Access,
+				Rule:        rule.Name,
+				Roles:       rule.Roles,
+				Permissions: rule.Permissions,
+			}
+		}
+	}
+
+	return Decision{Access: p.Default}
+}
+
+// Allows reports whether an authenticated caller holding the given roles and
+// permissions passes the decision.
+func (d Decision) Allows(roles, permissions []string) bool {
+	switch d.Access {
+	case Public:
+		return true
+	case Deny:
+		return false
+	}
+
+	if len(d.Roles) > 0 && !slices.ContainsFunc(d.Roles, func(role string) bool {
+		return slices.Contains(roles, role)
+	}) {
+		return false
+	}
+
+	for _, permission := range d.Permissions {
+		if !slices.Contains(permissions, permission) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r Rule) matches(method, host, requestPath string) bool {
+	if len(r.Methods) > 0 && !slices.Contains(r.Methods, method) {
+		return false
+	}
+	if r.Host != "" {
+		if ok, _ := path.Match(r.Host, host); !ok {
+			return false
+		}
+	}
+	if r.Path != "" && !matchPath(strings.Split(r.Path, "/"), strings.Split(requestPath, "/")) {
+		return false
+	}
+	return true
+}
+
+func matchPath(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchPath(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchPath(pattern[1:], segments[1:])
+}
+
+func validAccess(access Access) bool {
+	return access == Public || access == Authenticated || access == Deny
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// normalizePath drops the query string, decodes the path like net/url does
+// for the URL.Path routers match on, and cleans it, so that neither
+// "/public/../admin" nor "/%61dmin" can sneak past the rules. Paths that
+// do not decode are not valid and not normalized.
+func normalizePath(uri string) (string, bool) {
+	if i := strings.IndexAny(uri, "?#"); i >= 0 {
+		uri = uri[:i]
+	}
+	uri, err := url.PathUnescape(uri)
+	if err != nil {
+		return "", false
+	}
+	if uri == "" {
+		return "/", true
+	}
+	cleaned := path.Clean("/" + uri)
+	if strings.HasSuffix(uri, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned, true
+}
@@ -0,0 +1,139 @@
+package policy_test
+
+import (
+	"Auth/config"
+	"Auth/internal/policy"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `{
+  "default": "deny",
+  "rules": [
+    { "name": "health", "path": "/health", "access": "public" },
+    { "name": "assets", "host": "*.example.com", "path": "/static/**", "methods": ["get"], "access": "public" },
+    { "name": "admin", "path": "/admin/**", "roles": ["admin"] },
+    { "name": "articles-write", "path": "/api/articles/*", "methods": ["POST", "PUT"], "permissions": ["articles:write"] },
+    { "name": "api", "host": "api.example.com", "path": "/api/**" }
+  ]
+}`
+
+func mustParse(t *testing.T, document string) *policy.Policy {
+	t.Helper()
+	p, err := policy.Parse([]byte(document))
+	require.NoError(t, err)
+	return p
+}
+
+func TestPolicy_Evaluate(t *testing.T) {
+	p := mustParse(t, testPolicy)
+
+	tests := []struct {
+		req    policy.Request
+		rule   string
+		access policy.Access
+	}{
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/health"}, "health", policy.Public},
+		{policy.Request{Method: "GET", Host: "cdn.example.com:443", Path: "/static/css/site.css?v=2"}, "assets", policy.Public},
+		{policy.Request{Method: "POST", Host: "cdn.example.com", Path: "/static/upload"}, "", policy.Deny},
+		{policy.Request{Method: "GET", Host: "example.org", Path: "/static/site.css"}, "", policy.Deny},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/admin"}, "admin", policy.Authenticated},
+		{policy.Request{Method: "DELETE", Host: "example.com", Path: "/admin/users/1"}, "admin", policy.Authenticated},
+		{policy.Request{Method: "put", Host: "api.example.com", Path: "/api/articles/7"}, "articles-write", policy.Authenticated},
+		{policy.Request{Method: "GET", Host: "API.example.com", Path: "/api/articles/7"}, "api", policy.Authenticated},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/health/../admin/users"}, "admin", policy.Authenticated},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/%61dmin/users"}, "admin", policy.Authenticated},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/admin%2Fusers"}, "admin", policy.Authenticated},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/health%2F..%2Fadmin"}, "admin", policy.Authenticated},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/%68ealth?x=%zz"}, "health", policy.Public},
+		{policy.Request{Method: "GET", Host: "example.com", Path: "/health%zz"}, "", policy.Deny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.req.Method+" "+tt.req.Host+tt.req.Path, func(t *testing.T) {
+			decision := p.Evaluate(tt.req)
+			assert.Equal(t, tt.access, decision.Access)
+			assert.Equal(t, tt.rule, decision.Rule)
+		})
+	}
+}
+
+func TestPolicy_UndecodablePathIsDenied(t *testing.T) {
+	p := mustParse(t, `{"default": "public"}`)
+
+	assert.Equal(t, policy.Public, p.Evaluate(policy.Request{Method: "GET", Path: "/anything"}).Access)
+	assert.Equal(t, policy.Deny, p.Evaluate(policy.Request{Method: "GET", Path: "/any%zzthing"}).Access)
+}
+
+func TestPolicy_DefaultsToAuthenticated(t *testing.T) {
+	p := mustParse(t, `{"rules": [{"path": "/public/**", "access": "public"}]}`)
+
+	decision := p.Evaluate(policy.Request{Method: "GET", Path: "/private"})
+	assert.Equal(t, policy.Authenticated, decision.Access)
+
+	decision = p.Evaluate(policy.Request{Method: "GET", Path: "/public/index.html"})
+	assert.Equal(t, "#0", decision.Rule)
+}
+
+func TestPolicy_ParseRejectsInvalidPolicies(t *testing.T) {
+	documents := map[string]string{
+		"malformed":        `{"rules": [`,
+		"unknown default":  `{"default": "maybe"}`,
+		"unknown access":   `{"rules": [{"path": "/", "access": "sometimes"}]}`,
+		"roles on public":  `{"rules": [{"path": "/", "access": "public", "roles": ["admin"]}]}`,
+		"bad path pattern": `{"rules": [{"path": "/[a"}]}`,
+		"bad host pattern": `{"rules": [{"host": "[a"}]}`,
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			_, err := policy.Parse([]byte(document))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecision_Allows(t *testing.T) {
+	p := mustParse(t, testPolicy)
+
+	admin := p.Evaluate(policy.Request{Method: "GET", Path: "/admin"})
+	assert.True(t, admin.Allows([]string{"editor", "admin"}, nil))
+	assert.False(t, admin.Allows([]string{"editor"}, nil))
+
+	write := p.Evaluate(policy.Request{Method: "POST", Path: "/api/articles/1"})
+	assert.True(t, write.Allows(nil, []string{"articles:write"}))
+	assert.False(t, write.Allows(nil, []string{"articles:read"}))
+
+	assert.True(t, p.Evaluate(policy.Request{Method: "GET", Path: "/health"}).Allows(nil, nil))
+	assert.False(t, p.Evaluate(policy.Request{Method: "GET", Path: "/other"}).Allows([]string{"admin"}, nil))
+}
+
+func TestEngine_Reload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"default": "deny"}`), 0o600))
+
+	engine, err := policy.Load(config.PolicyConfig{File: file})
+	require.NoError(t, err)
+	assert.Equal(t, policy.Deny, engine.Evaluate(policy.Request{Method: "GET", Path: "/"}).Access)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{"rules": [{"path": "/**", "access": "public"}]}`), 0o600))
+	require.NoError(t, engine.Reload())
+	assert.Equal(t, policy.Public, engine.Evaluate(policy.Request{Method: "GET", Path: "/"}).Access)
+	assert.Equal(t, 1, engine.Rules())
+
+	// a broken file keeps the policy loaded before
+	require.NoError(t, os.WriteFile(file, []byte(`{"rules": [`), 0o600))
+	assert.Error(t, engine.Reload())
+	assert.Equal(t, policy.Public, engine.Evaluate(policy.Request{Method: "GET", Path: "/"}).Access)
+}
+
+func TestLoad_WithoutFileRequiresAuthentication(t *testing.T) {
+	engine, err := policy.Load(config.PolicyConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, policy.Authenticated, engine.Evaluate(policy.Request{Method: "GET", Path: "/anything"}).Access)
+	assert.NoError(t, engine.Reload())
+}
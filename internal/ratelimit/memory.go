@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryCounter keeps counters in the process. Each instance of the service
+// counts on its own, so it is meant as a fallback and for local setups.
+type MemoryCounter struct {
+	mu        sync.Mutex
+	counters  map[string]memoryEntry
+	nextSweep time.Time
+}
+
+type memoryEntry struct {
+	count     int64
+	expiresAt time.Time
+}
+
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{counters: map[string]memoryEntry{}}
+}
+
+func (m *MemoryCounter) Increment(key string, expiration time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	entry, ok := m.counters[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = memoryEntry{expiresAt: now.Add(expiration)}
+	}
+	entry.count++
+	m.counters[key] = entry
+	return entry.count, nil
+}
+
+func (m *MemoryCounter) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.counters[key]
+	if !ok || !time.Now().Before(entry.expiresAt) {
+		return "", errors.New("not found")
+	}
+	return strconv.FormatInt(entry.count, 10), nil
+}
+
+// sweep drops expired counters once a minute, keys of clients that went
+// away would pile up otherwise.
+func (m *MemoryCounter) sweep(now time.Time) {
+	if now.Before(m.nextSweep) {
+		return
+	}
+	for key, entry := range m.counters {
+		if !now.Before(entry.expiresAt) {
+			delete(m.counters, key)
+		}
+	}
+	m.nextSweep = now.Add(time.Minute)
+}
+
+// fallbackCounter counts in memory while the primary counter fails, so that
+// an outage of Redis neither rejects every request nor lifts the limits.
+type fallbackCounter struct {
+	primary  Counter
+	fallback Counter
+	failing  sync.Once
+}
+
+// WithFallback returns a counter using fallback whenever primary fails.
+func WithFallback(primary, fallback Counter) Counter {
+	return &fallbackCounter{primary: primary, fallback: fallback}
+}
+
+func (f *fallbackCounter) Increment(key string, expiration time.Duration) (int64, error) {
+	count, err := f.primary.Increment(key, expiration)
+	if err != nil {
+		f.failing.Do(func() { log.Printf("rate limit counter failed, counting in memory: %v", err) })
+		return f.fallback.Increment(key, expiration)
+	}
+	return count, nil
+}
+
+func (f *fallbackCounter) Get(key string) (string, error) {
+	if val, err := f.primary.Get(key); err == nil {
+		return val, nil
+	}
+	return f.fallback.Get(key)
+}
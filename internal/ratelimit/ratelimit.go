@@ -0,0 +1,177 @@
+package ratelimit
+
+import (
+	"Auth/config"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key names what requests are counted by.
+type Key string
+
+const (
+	// IP counts requests per client IP.
+	IP Key = "ip"
+	// Username counts requests per username in the request body.
+	Username Key = "username"
+	// ClientID counts requests per OAuth client.
+	ClientID Key = "client_id"
+)
+
+// Limit allows Requests per Window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+// Rule limits the requests of a route sharing the same key.
+type Rule struct {
+	Route string
+	Key   Key
+	Limit Limit
+}
+
+// Result is the outcome of counting a request. Reset is when the current
+// window ends, RetryAfter how long a rejected client has to wait.
+type Result struct {
+	Allowed    bool
+	Limit      Limit
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Counter keeps the counters of the limiter, *pkg.RedisRepository shares
+// them between instances of the service.
+type Counter interface {
+	Increment(key string, expiration time.Duration) (int64, error)
+	Get(key string) (string, error)
+}
+
+// Limiter applies rules with a sliding window: the count of the previous
+// fixed window is weighted by how much of it still overlaps the sliding one.
+// This smooths bursts at window edges at the cost of two counters per key.
+type Limiter struct {
+	counter Counter
+	rules   map[string][]Rule
+	now     func() time.Time
+}
+
+func NewLimiter(counter Counter, rules []Rule) *Limiter {
+	limiter := &Limiter{counter: counter, rules: map[string][]Rule{}, now: time.Now}
+	for _, rule := range rules {
+		limiter.rules[rule.Route] = append(limiter.rules[rule.Route], rule)
+	}
+	return limiter
+}
+
+// New builds a limiter from RATE_LIMITS.
+func New(cfg config.RateLimitConfig, counter Counter) (*Limiter, error) {
+	rules, err := ParseRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return NewLimiter(counter, rules), nil
+}
+
+// ParseRules parses comma separated rules of the form route:key=requests/window,
+// e.g. "login:ip=20/1m,login:username=10/1m,register:ip=5/1h".
+func ParseRules(value string) ([]Rule, error) {
+	rules := []Rule{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		target, limit, ok := strings.Cut(item, "=")
+		route, key, ok2 := strings.Cut(target, ":")
+		requests, window, ok3 := strings.Cut(limit, "/")
+		if !ok || !ok2 || !ok3 || route == "" {
+			return nil, fmt.Errorf("invalid rate limit %q, expected route:key=requests/window", item)
+		}
+
+		switch Key(key) {
+		case IP, Username, ClientID:
+		default:
+			return nil, fmt.Errorf("invalid rate limit %q: unknown key %q", item, key)
+		}
+
+		count, err := strconv.Atoi(requests)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: requests must be a positive number", item)
+		}
+		duration, err := time.ParseDuration(window)
+		if err != nil || duration < time.Second {
+			return nil, fmt.Errorf("invalid rate limit %q: window must be a duration of at least 1s", item)
+		}
+
+		rules = append(rules, Rule{Route: route, Key: Key(key), Limit: Limit{Requests: count, Window: duration}})
+	}
+	return rules, nil
+}
+
+// Rules returns the rules of a route.
+func (l *Limiter) Rules(route string) []Rule {
+	return l.rules[route]
+}
+
+// Allow counts a request of value, e.g. a client IP, under the rule.
+// Rejected requests are counted too, so that a client hammering the route
+// stays limited.
+func (l *Limiter) Allow(rule Rule, value string) (Result, error) {
+	window := rule.Limit.Window
+	now := l.now()
+	start := now.Truncate(window)
+	elapsed := now.Sub(start)
+
+	prefix := fmt.Sprintf("rate_limit:%s:%s:%s:", rule.Route, rule.Key, value)
+	current, err := l.counter.Increment(prefix+strconv.FormatInt(start.Unix(), 10), 2*window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var previous int64
+	if val, err := l.counter.Get(prefix + strconv.FormatInt(start.Add(-window).Unix(), 10)); err == nil {
+		previous, _ = strconv.ParseInt(val, 10, 64)
+	}
+
+	limit := float64(rule.Limit.Requests)
+	overlap := 1 - float64(elapsed)/float64(window)
+	estimate := float64(previous)*overlap + float64(current)
+
+	result := Result{
+		Allowed:   estimate <= limit,
+		Limit:     rule.Limit,
+		Remaining: max(int(math.Floor(limit-estimate)), 0),
+		Reset:     window - elapsed,
+	}
+	if !result.Allowed {
+		result.RetryAfter = retryAfter(window, elapsed, float64(previous), float64(current), limit)
+	}
+	return result, nil
+}
+
+// retryAfter returns how long until one more request fits under the limit,
+// either while the previous window fades out or in the next one.
+func retryAfter(window, elapsed time.Duration, previous, current, limit float64) time.Duration {
+	if previous > 0 && current+1 <= limit {
+		fraction := 1 - (limit-current-1)/previous
+		if wait := time.Duration(fraction*float64(window)) - elapsed; wait < window-elapsed {
+			return max(wait, 0)
+		}
+	}
+
+	wait := window - elapsed
+	if current+1 > limit {
+		wait += time.Duration((1 - (limit-1)/current) * float64(window))
+	}
+	return wait
+}
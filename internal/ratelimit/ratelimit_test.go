@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(counter Counter, now *time.Time) (*Limiter, Rule) {
+	rule := Rule{Route: "login", Key: IP, Limit: Limit{Requests: 3, Window: time.Minute}}
+	limiter := NewLimiter(counter, []Rule{rule})
+	limiter.now = func() time.Time { return *now }
+	return limiter, rule
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("login:ip=20/1m, login:username=10/1m,,register:ip=5/1h")
+	require.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Route: "login", Key: IP, Limit: Limit{Requests: 20, Window: time.Minute}},
+		{Route: "login", Key: Username, Limit: Limit{Requests: 10, Window: time.Minute}},
+		{Route: "register", Key: IP, Limit: Limit{Requests: 5, Window: time.Hour}},
+	}, rules)
+
+	for _, invalid := range []string{"login", "login:ip=20", "login:email=1/1m", "login:ip=0/1m", "login:ip=5/10ms", ":ip=5/1m"} {
+		_, err := ParseRules(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLimiter_RejectsOverTheLimit(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 15, 0, time.UTC)
+	limiter, rule := newTestLimiter(NewMemoryCounter(), &now)
+
+	for i := 2; i >= 0; i-- {
+		result, err := limiter.Allow(rule, "10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, i, result.Remaining)
+		assert.Equal(t, 45*time.Second, result.Reset)
+	}
+
+	result, err := limiter.Allow(rule, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Greater(t, result.RetryAfter, 45*time.Second)
+
+	result, _ = limiter.Allow(rule, "10.0.0.2")
+	assert.True(t, result.Allowed)
+}
+
+func TestLimiter_PreviousWindowFadesOut(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 50, 0, time.UTC)
+	limiter, rule := newTestLimiter(NewMemoryCounter(), &now)
+	for i := 0; i < 3; i++ {
+		limiter.Allow(rule, "10.0.0.1")
+	}
+
+	// a third into the next window two thirds of the previous count remain
+	now = time.Date(2026, 1, 1, 12, 1, 20, 0, time.UTC)
+	result, _ := limiter.Allow(rule, "10.0.0.1")
+	assert.True(t, result.Allowed)
+	result, _ = limiter.Allow(rule, "10.0.0.1")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 40*time.Second, result.RetryAfter)
+
+	now = time.Date(2026, 1, 1, 12, 2, 50, 0, time.UTC)
+	result, _ = limiter.Allow(rule, "10.0.0.1")
+	assert.True(t, result.Allowed)
+}
+
+type failingCounter struct{}
+
+func (failingCounter) Increment(string, time.Duration) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func (failingCounter) Get(string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestWithFallback_CountsInMemoryWhenPrimaryFails(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter, rule := newTestLimiter(WithFallback(failingCounter{}, NewMemoryCounter()), &now)
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(rule, "10.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, _ := limiter.Allow(rule, "10.0.0.1")
+	assert.False(t, result.Allowed)
+}
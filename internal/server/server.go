@@ -3,23 +3,91 @@ package server
 import (
 	"Auth/config"
 	"Auth/internal/handler/auth"
+	"Auth/internal/identity"
+	"Auth/internal/notify"
+	"Auth/internal/passwords"
+	"Auth/internal/policy"
+	"Auth/internal/ratelimit"
+	"Auth/internal/signing"
 	"Auth/pkg"
+	"log"
 
 	"github.com/gin-gonic/gin"
 )
 
-func StartServer(db *pkg.UserGormRepository, redis *pkg.RedisRepository, cfg *config.Config) *gin.Engine {
+func StartServer(
+	db *pkg.UserGormRepository,
+	redis *pkg.RedisRepository,
+	keys *signing.KeyRing,
+	policies *policy.Engine,
+	limiter *ratelimit.Limiter,
+	usernamePolicy *identity.UsernamePolicy,
+	passwordPolicy *passwords.Policy,
+	notifier notify.Notifier,
+	cfg *config.Config,
+) *gin.Engine {
 	if cfg.Env == "prod" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	clients := pkg.NewClientGormRepository(db)
+	apiKeys := pkg.NewAPIKeyGormRepository(db)
+
 	router := gin.Default()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("invalid trusted proxies: %v", err)
+	}
+	authenticated := auth.AuthHandler(keys, redis, db, apiKeys, clients, cfg.Session)
+
+	// routes managing the account itself or, for admins, other users and
+	// the service; API keys are not accepted here
+	account := router.Group("", authenticated, auth.RequireToken())
+	admin := account.Group("/admin", auth.RequireRole("admin"))
 
 	router.GET("/health", func(c *gin.Context) { c.JSON(200, gin.H{"status": "ok"}) })
-	router.POST("/login", auth.LoginHandler(db, cfg.JWT, redis))
-	router.POST("/register", auth.RegisterHandler(db, cfg.JWT, redis))
-	router.GET("/auth", auth.AuthHandler(cfg.JWT.Secret, redis)) // traefik sends get req
-	router.DELETE("/unregister", auth.AuthHandler(cfg.JWT.Secret, redis), auth.UnregisterHandler(db, redis, cfg.JWT))
+	router.GET("/.well-known/jwks.json", auth.JWKSHandler(keys))
+	router.GET("/.well-known/openid-configuration", auth.OpenIDConfigurationHandler(cfg.OIDC, keys))
+	router.POST("/login", auth.RateLimitHandler(limiter, "login"), auth.LoginHandler(db, cfg.JWT, cfg.Lockout, cfg.Email, cfg.Session, keys, redis))
+	router.POST("/login/mfa", auth.RateLimitHandler(limiter, "login_mfa"), auth.LoginMFAHandler(db, cfg.JWT, cfg.Session, keys, redis))
+	router.POST("/register", auth.RateLimitHandler(limiter, "register"), auth.RegisterHandler(db, cfg.JWT, cfg.Email, usernamePolicy, passwordPolicy, keys, redis, notifier))
+	router.GET("/verify-email", auth.VerifyEmailHandler(db, keys, redis))
+	router.POST("/verify-email", auth.VerifyEmailHandler(db, keys, redis))
+	router.POST("/verify-email/resend", auth.RateLimitHandler(limiter, "verify_email_resend"), auth.ResendVerificationHandler(db, cfg.Email, keys, redis, notifier))
+	router.POST("/token/refresh", auth.RefreshTokenHandler(db, cfg.JWT, keys, redis))
+	router.GET("/auth", auth.PolicyHandler(policies), authenticated, auth.AuthorizeHandler(), auth.ForwardAuthHandler(cfg.ForwardAuth)) // traefik sends get req
+	account.DELETE("/unregister", auth.UnregisterHandler(db, redis))
+	account.POST("/logout", auth.LogoutHandler(cfg.JWT, cfg.Session, redis))
+	account.POST("/logout/all", auth.LogoutAllHandler(db, redis))
+	account.PUT("/password", auth.ChangePasswordHandler(db, passwordPolicy, redis))
+	router.POST("/password/forgot", auth.RateLimitHandler(limiter, "password_forgot"), auth.ForgotPasswordHandler(db, cfg.Password, redis, notifier))
+	router.POST("/password/reset", auth.RateLimitHandler(limiter, "password_reset"), auth.ResetPasswordHandler(db, cfg.Password, passwordPolicy, redis))
+	account.POST("/mfa/totp/enroll", auth.TOTPEnrollHandler(db, cfg.MFA, redis))
+	account.POST("/mfa/totp/verify", auth.TOTPVerifyHandler(db, redis))
+	admin.POST("/oauth/clients", auth.OAuthClientRegisterHandler(clients))
+	router.GET("/oauth/authorize", auth.OAuthAuthorizeHandler(clients))
+	router.POST("/oauth/authorize", auth.RateLimitHandler(limiter, "oauth_authorize"), auth.OAuthConsentHandler(clients, db, cfg.Lockout, cfg.Email, redis))
+	router.POST("/oauth/token", auth.RateLimitHandler(limiter, "oauth_token"), auth.OAuthTokenHandler(clients, db, cfg.JWT, cfg.OIDC, keys, redis))
+	router.POST("/introspect", auth.OAuthIntrospectHandler(clients, db, keys, redis))
+	router.POST("/revoke", auth.OAuthRevokeHandler(clients, cfg.JWT, keys, redis))
+	account.POST("/api-keys", auth.APIKeyCreateHandler(apiKeys, db, redis))
+	account.GET("/api-keys", auth.APIKeyListHandler(apiKeys))
+	account.DELETE("/api-keys/:id", auth.APIKeyRevokeHandler(apiKeys))
+	account.GET("/sessions", auth.SessionListHandler(cfg.Session, redis))
+	account.DELETE("/sessions/:id", auth.SessionRevokeHandler(cfg.JWT, cfg.Session, redis))
+	admin.GET("/lockouts/users/:username", auth.LockoutStatusHandler(redis))
+	admin.DELETE("/lockouts/users/:username", auth.UnlockHandler(redis))
+	admin.GET("/lockouts/ips/:ip", auth.LockoutStatusHandler(redis))
+	admin.DELETE("/lockouts/ips/:ip", auth.UnlockHandler(redis))
+	router.GET("/userinfo", authenticated, auth.UserInfoHandler(db, redis))
+	admin.GET("/users", auth.AdminUserListHandler(db))
+	admin.GET("/users/:id", auth.AdminUserGetHandler(db))
+	admin.GET("/users/by-username/:username", auth.AdminUserGetHandler(db))
+	admin.PUT("/users/:id/status", auth.AdminUserStatusHandler(db, redis))
+	admin.PUT("/users/:id/roles", auth.AdminUserRolesHandler(db, redis))
+	admin.POST("/users/:id/logout", auth.AdminUserLogoutHandler(db, redis))
+	admin.POST("/users/:id/password-reset", auth.AdminUserPasswordResetHandler(db, cfg.Password, redis, notifier))
+	admin.DELETE("/users/:id", auth.AdminUserDeleteHandler(db, redis))
+	admin.POST("/policy/evaluate", auth.PolicyEvaluateHandler(policies))
 
 	return router
 }
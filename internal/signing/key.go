@@ -0,0 +1,130 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a single JWT signing key. For HMAC the same secret signs and
+// verifies, for asymmetric algorithms only the public half is ever exposed.
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   any
+	verifyKey any
+}
+
+const DefaultHMACKeyID = "default"
+
+func NewHMACKey(id, secret string) (*Key, error) {
+	if secret == "" {
+		return nil, errors.New("secret cannot be empty")
+	}
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}, nil
+}
+
+// ParsePrivateKey reads a PEM encoded PKCS#8, PKCS#1 or SEC 1 private key and
+// checks that it can be used with the given algorithm. When id is empty the
+// RFC 7638 thumbprint of the public key is used as the key id.
+func ParsePrivateKey(id, alg string, pemBytes []byte) (*Key, error) {
+	method := jwt.GetSigningMethod(alg)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported signing method: %s", alg)
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	private, err := parsePrivateKeyBlock(block)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+
+	if err := checkKeyMatchesMethod(method, signer.Public()); err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		ID:        id,
+		Method:    method,
+		signKey:   private,
+		verifyKey: signer.Public(),
+	}
+
+	if key.ID == "" {
+		jwk, err := key.JWK()
+		if err != nil {
+			return nil, err
+		}
+		key.ID = jwk.Thumbprint()
+	}
+
+	return key, nil
+}
+
+func (k *Key) IsSymmetric() bool {
+	_, ok := k.Method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+func parsePrivateKeyBlock(block *pem.Block) (any, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+}
+
+func checkKeyMatchesMethod(method jwt.SigningMethod, public crypto.PublicKey) error {
+	switch m := method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := public.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("%s requires an RSA key", method.Alg())
+		}
+	case *jwt.SigningMethodECDSA:
+		pub, ok := public.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an ECDSA key", method.Alg())
+		}
+		if pub.Curve.Params().BitSize != m.CurveBits {
+			return fmt.Errorf("%s requires a P-%d key", method.Alg(), m.CurveBits)
+		}
+	case *jwt.SigningMethodEd25519:
+		if _, ok := public.(ed25519.PublicKey); !ok {
+			return fmt.Errorf("%s requires an Ed25519 key", method.Alg())
+		}
+	default:
+		return fmt.Errorf("%s is not an asymmetric signing method", method.Alg())
+	}
+	return nil
+}
+
+func curveName(curve elliptic.Curve) string {
+	return curve.Params().Name
+}
@@ -0,0 +1,187 @@
+package signing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeyRing holds the key used to sign new tokens and every key tokens may
+// still be verified with, indexed by kid.
+//
+// Keys dropped from the ring are not forgotten right away: they are retired
+// and keep verifying for the retention period, which should match the longest
+// lifetime of a token signed with them.
+type KeyRing struct {
+	mu        sync.RWMutex
+	current   *Key
+	keys      map[string]*Key
+	retired   map[string]retiredKey
+	retention time.Duration
+	source    func() (*Key, []*Key, error)
+}
+
+type retiredKey struct {
+	key       *Key
+	expiresAt time.Time
+}
+
+func NewKeyRing(retention time.Duration, current *Key, active ...*Key) *KeyRing {
+	r := &KeyRing{retention: retention, retired: map[string]retiredKey{}}
+	r.Replace(current, active...)
+	return r
+}
+
+// NewHMACKeyRing is a shortcut for a ring holding a single HS256 secret.
+func NewHMACKeyRing(secret string) (*KeyRing, error) {
+	key, err := NewHMACKey(DefaultHMACKeyID, secret)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyRing(0, key), nil
+}
+
+// Replace swaps the signing key and the set of active verification keys.
+// Previously known keys missing from the new set are retired.
+func (r *KeyRing) Replace(current *Key, active ...*Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	keys := map[string]*Key{current.ID: current}
+	for _, key := range active {
+		keys[key.ID] = key
+	}
+
+	retired := map[string]retiredKey{}
+	for id, old := range r.retired {
+		if _, ok := keys[id]; !ok && now.Before(old.expiresAt) {
+			retired[id] = old
+		}
+	}
+	for id, key := range r.keys {
+		if _, ok := keys[id]; !ok {
+			retired[id] = retiredKey{key: key, expiresAt: now.Add(r.retention)}
+		}
+	}
+
+	r.current = current
+	r.keys = keys
+	r.retired = retired
+}
+
+// Reload re-reads the keys from the source the ring was loaded from.
+func (r *KeyRing) Reload() error {
+	if r.source == nil {
+		return errors.New("key ring has no source to reload from")
+	}
+
+	current, active, err := r.source()
+	if err != nil {
+		return err
+	}
+
+	r.Replace(current, active...)
+	return nil
+}
+
+// CurrentKeyID returns the kid new tokens are signed with.
+func (r *KeyRing) CurrentKeyID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.ID
+}
+
+// CurrentAlgorithm returns the alg new tokens are signed with.
+func (r *KeyRing) CurrentAlgorithm() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.Method.Alg()
+}
+
+// CurrentIsSymmetric reports whether new tokens are signed with a shared
+// secret, which only this server can verify them with.
+func (r *KeyRing) CurrentIsSymmetric() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.IsSymmetric()
+}
+
+// Sign signs the claims with the current key and stamps its kid into the
+// token header.
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	r.mu.RLock()
+	key := r.current
+	r.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signKey)
+}
+
+// Keyfunc resolves the verification key for a parsed token. Tokens without a
+// kid header are checked against the current key, so tokens issued before
+// kids were introduced keep working.
+func (r *KeyRing) Keyfunc(token *jwt.Token) (any, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key := r.current
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		if key = r.lookup(kid); key == nil {
+			return nil, ErrUnknownKey
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+// JWKS returns the public keys of every asymmetric key in the ring, retired
+// ones included, so that downstream verifiers can still check older tokens.
+func (r *KeyRing) JWKS() JWKSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range r.verificationKeys() {
+		if jwk, err := key.JWK(); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func (r *KeyRing) lookup(kid string) *Key {
+	if key, ok := r.keys[kid]; ok {
+		return key
+	}
+	if old, ok := r.retired[kid]; ok && time.Now().Before(old.expiresAt) {
+		return old.key
+	}
+	return nil
+}
+
+func (r *KeyRing) verificationKeys() []*Key {
+	now := time.Now()
+	keys := make([]*Key, 0, len(r.keys)+len(r.retired))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+	for _, old := range r.retired {
+		if now.Before(old.expiresAt) {
+			keys = append(keys, old.key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,230 @@
+package signing_test
+
+import (
+	"Auth/config"
+	"Auth/internal/signing"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pemEncode(t *testing.T, key any) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func generateKeys(t *testing.T) map[string]any {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	return map[string]any{"RS256": rsaKey, "ES256": ecKey, "EdDSA": edKey}
+}
+
+func TestKeyRing_SignAndVerifyAsymmetric(t *testing.T) {
+	for alg, private := range generateKeys(t) {
+		t.Run(alg, func(t *testing.T) {
+			key, err := signing.ParsePrivateKey("", alg, pemEncode(t, private))
+			require.NoError(t, err)
+			assert.NotEmpty(t, key.ID)
+
+			ring := signing.NewKeyRing(0, key)
+			tokenString, err := ring.Sign(jwt.MapClaims{"username": "alice", "exp": time.Now().Add(time.Minute).Unix()})
+			require.NoError(t, err)
+
+			token, err := jwt.Parse(tokenString, ring.Keyfunc)
+			require.NoError(t, err)
+			assert.Equal(t, key.ID, token.Header["kid"])
+			assert.Equal(t, alg, token.Header["alg"])
+
+			jwks := ring.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, key.ID, jwks.Keys[0].Kid)
+			assert.Equal(t, alg, jwks.Keys[0].Alg)
+			assert.Equal(t, key.ID, jwks.Keys[0].Thumbprint())
+		})
+	}
+}
+
+func TestParsePrivateKey_RejectsMismatchedAlgorithm(t *testing.T) {
+	keys := generateKeys(t)
+
+	_, err := signing.ParsePrivateKey("kid", "ES256", pemEncode(t, keys["RS256"]))
+	assert.ErrorContains(t, err, "requires an ECDSA key")
+
+	_, err = signing.ParsePrivateKey("kid", "RS256", pemEncode(t, keys["EdDSA"]))
+	assert.ErrorContains(t, err, "requires an RSA key")
+
+	_, err = signing.ParsePrivateKey("kid", "ES384", pemEncode(t, keys["ES256"]))
+	assert.ErrorContains(t, err, "requires a P-384 key")
+
+	_, err = signing.ParsePrivateKey("kid", "none", pemEncode(t, keys["ES256"]))
+	assert.Error(t, err)
+}
+
+func TestKeyRing_RejectsAlgorithmConfusion(t *testing.T) {
+	key, err := signing.ParsePrivateKey("rsa", "RS256", pemEncode(t, generateKeys(t)["RS256"]))
+	require.NoError(t, err)
+	ring := signing.NewKeyRing(0, key)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "mallory"})
+	forged.Header["kid"] = "rsa"
+	tokenString, err := forged.SignedString([]byte("guessed"))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(tokenString, ring.Keyfunc)
+	assert.ErrorContains(t, err, "unexpected signing method")
+}
+
+func TestKeyRing_UnknownKid(t *testing.T) {
+	ring, err := signing.NewHMACKeyRing("secret")
+	require.NoError(t, err)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"username": "alice"})
+	token.Header["kid"] = "other"
+	tokenString, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(tokenString, ring.Keyfunc)
+	assert.ErrorIs(t, err, signing.ErrUnknownKey)
+}
+
+func TestKeyRing_HMACIsNotPublished(t *testing.T) {
+	ring, err := signing.NewHMACKeyRing("secret")
+	require.NoError(t, err)
+
+	assert.Empty(t, ring.JWKS().Keys)
+}
+
+func TestLoadKeyRing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pemEncode(t, generateKeys(t)["ES256"]), 0o600))
+
+	ring, err := signing.LoadKeyRing(config.JWTConfig{SigningMethod: "ES256", PrivateKeyFile: path, KeyID: "es-1"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "es-1", ring.JWKS().Keys[0].Kid)
+
+	_, err = signing.LoadKeyRing(config.JWTConfig{SigningMethod: "HS256"}, 0)
+	assert.ErrorContains(t, err, "secret cannot be empty")
+
+	_, err = signing.LoadKeyRing(config.JWTConfig{SigningMethod: "RS256", PrivateKeyFile: filepath.Join(t.TempDir(), "missing.pem")}, 0)
+	assert.ErrorContains(t, err, "could not read private key")
+}
+
+func TestKeyRing_RetiredKeyVerifiesUntilRetentionEnds(t *testing.T) {
+	oldKey, err := signing.NewHMACKey("old", "old-secret")
+	require.NoError(t, err)
+	newKey, err := signing.NewHMACKey("new", "new-secret")
+	require.NoError(t, err)
+
+	ring := signing.NewKeyRing(time.Hour, oldKey)
+	oldToken, err := ring.Sign(jwt.MapClaims{"username": "alice"})
+	require.NoError(t, err)
+
+	ring.Replace(newKey)
+	assert.Equal(t, "new", ring.CurrentKeyID())
+
+	_, err = jwt.Parse(oldToken, ring.Keyfunc)
+	assert.NoError(t, err)
+
+	newToken, err := ring.Sign(jwt.MapClaims{"username": "alice"})
+	require.NoError(t, err)
+	parsed, err := jwt.Parse(newToken, ring.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, "new", parsed.Header["kid"])
+}
+
+func TestKeyRing_RetiredKeyAgesOut(t *testing.T) {
+	oldKey, err := signing.NewHMACKey("old", "old-secret")
+	require.NoError(t, err)
+	newKey, err := signing.NewHMACKey("new", "new-secret")
+	require.NoError(t, err)
+
+	ring := signing.NewKeyRing(0, oldKey)
+	oldToken, err := ring.Sign(jwt.MapClaims{"username": "alice"})
+	require.NoError(t, err)
+
+	ring.Replace(newKey)
+
+	_, err = jwt.Parse(oldToken, ring.Keyfunc)
+	assert.ErrorIs(t, err, signing.ErrUnknownKey)
+}
+
+func TestKeyRing_ActiveKeysKeepVerifying(t *testing.T) {
+	oldKey, err := signing.NewHMACKey("old", "old-secret")
+	require.NoError(t, err)
+	newKey, err := signing.NewHMACKey("new", "new-secret")
+	require.NoError(t, err)
+
+	ring := signing.NewKeyRing(0, oldKey)
+	oldToken, err := ring.Sign(jwt.MapClaims{"username": "alice"})
+	require.NoError(t, err)
+
+	ring.Replace(newKey, oldKey)
+
+	_, err = jwt.Parse(oldToken, ring.Keyfunc)
+	assert.NoError(t, err)
+}
+
+func TestLoadKeyRing_ReloadsManifest(t *testing.T) {
+	dir := t.TempDir()
+	keys := generateKeys(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rsa.pem"), pemEncode(t, keys["RS256"]), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ec.pem"), pemEncode(t, keys["ES256"]), 0o600))
+
+	manifest := filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(manifest, []byte(`{
+		"current": "rsa-1",
+		"keys": [{"kid": "rsa-1", "alg": "RS256", "private_key_file": "rsa.pem"}]
+	}`), 0o600))
+
+	ring, err := signing.LoadKeyRing(config.JWTConfig{KeysFile: manifest}, 15*time.Minute)
+	require.NoError(t, err)
+	oldToken, err := ring.Sign(jwt.MapClaims{"username": "alice"})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(manifest, []byte(`{
+		"current": "ec-1",
+		"keys": [{"kid": "ec-1", "alg": "ES256", "private_key_file": "ec.pem"}]
+	}`), 0o600))
+	require.NoError(t, ring.Reload())
+
+	assert.Equal(t, "ec-1", ring.CurrentKeyID())
+	_, err = jwt.Parse(oldToken, ring.Keyfunc)
+	assert.NoError(t, err)
+
+	kids := []string{}
+	for _, jwk := range ring.JWKS().Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	assert.Equal(t, []string{"ec-1", "rsa-1"}, kids)
+}
+
+func TestLoadKeyRing_InvalidManifest(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "keys.json")
+	require.NoError(t, os.WriteFile(manifest, []byte(`{
+		"current": "missing",
+		"keys": [{"kid": "hs-1", "alg": "HS256", "secret": "s3cr3t"}]
+	}`), 0o600))
+
+	_, err := signing.LoadKeyRing(config.JWTConfig{KeysFile: manifest}, 0)
+	assert.ErrorContains(t, err, `current key "missing" not found`)
+}
@@ -0,0 +1,129 @@
+package signing
+
+import (
+	"Auth/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// keyManifest is the format of JWT_KEYS_FILE. Every listed key verifies
+// tokens, the one named by Current also signs them. Relative key file paths
+// are resolved against the manifest directory.
+type keyManifest struct {
+	Current string `json:"current"`
+	Keys    []struct {
+		Kid            string `json:"kid"`
+		Alg            string `json:"alg"`
+		PrivateKeyFile string `json:"private_key_file"`
+		Secret         string `json:"secret"`
+	} `json:"keys"`
+}
+
+// LoadKeyRing builds the key ring described by the JWT configuration. When
+// JWT_KEYS_FILE is set the keys come from that manifest. Otherwise HS256 with
+// JWT_SECRET stays the default and any other algorithm requires a PEM encoded
+// private key in JWT_PRIVATE_KEY_FILE.
+//
+// The returned ring can be reloaded, keys that disappear are retired for
+// retention, the longest lifetime of a token they sign, so that those tokens
+// stay valid until expiry.
+func LoadKeyRing(cfg config.JWTConfig, retention time.Duration) (*KeyRing, error) {
+	source := func() (*Key, []*Key, error) {
+		if cfg.KeysFile != "" {
+			return loadManifest(cfg.KeysFile)
+		}
+		key, err := loadSingleKey(cfg)
+		return key, nil, err
+	}
+
+	current, active, err := source()
+	if err != nil {
+		return nil, err
+	}
+
+	ring := NewKeyRing(retention, current, active...)
+	ring.source = source
+	return ring, nil
+}
+
+func loadSingleKey(cfg config.JWTConfig) (*Key, error) {
+	if cfg.SigningMethod == "" || cfg.SigningMethod == "HS256" {
+		id := cfg.KeyID
+		if id == "" {
+			id = DefaultHMACKeyID
+		}
+		return NewHMACKey(id, cfg.Secret)
+	}
+
+	pemBytes, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key: %w", err)
+	}
+
+	return ParsePrivateKey(cfg.KeyID, cfg.SigningMethod, pemBytes)
+}
+
+func loadManifest(path string) (*Key, []*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read keys file: %w", err)
+	}
+
+	var manifest keyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("invalid keys file: %w", err)
+	}
+
+	var current *Key
+	var active []*Key
+	seen := map[string]bool{}
+
+	for _, entry := range manifest.Keys {
+		if entry.Kid == "" {
+			return nil, nil, errors.New("every key in the keys file needs a kid")
+		}
+		if seen[entry.Kid] {
+			return nil, nil, fmt.Errorf("duplicate kid %q in keys file", entry.Kid)
+		}
+		seen[entry.Kid] = true
+
+		var key *Key
+		if entry.Alg == "HS256" {
+			key, err = NewHMACKey(entry.Kid, entry.Secret)
+		} else {
+			key, err = loadManifestKey(filepath.Dir(path), entry.Kid, entry.Alg, entry.PrivateKeyFile)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("key %q: %w", entry.Kid, err)
+		}
+
+		if entry.Kid == manifest.Current {
+			current = key
+		} else {
+			active = append(active, key)
+		}
+	}
+
+	if current == nil {
+		return nil, nil, fmt.Errorf("current key %q not found in keys file", manifest.Current)
+	}
+
+	return current, active, nil
+}
+
+func loadManifestKey(dir, kid, alg, file string) (*Key, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+
+	pemBytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key: %w", err)
+	}
+
+	return ParsePrivateKey(kid, alg, pemBytes)
+}
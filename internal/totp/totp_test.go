@@ -0,0 +1,71 @@
+package totp_test
+
+import (
+	"Auth/internal/totp"
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 appendix B, SHA1 secret, truncated to 6 digits.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "time %d", unix)
+	}
+}
+
+func TestValidate_AcceptsNeighbouringSteps(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	previous, err := totp.Code(rfcSecret, totp.Step(now)-1)
+	require.NoError(t, err)
+	step, ok := totp.Validate(rfcSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now)-1, step)
+
+	tooOld, err := totp.Code(rfcSecret, totp.Step(now)-2)
+	require.NoError(t, err)
+	_, ok = totp.Validate(rfcSecret, tooOld, now)
+	assert.False(t, ok)
+}
+
+func TestValidate_RejectsMalformedInput(t *testing.T) {
+	now := time.Now()
+
+	_, ok := totp.Validate(rfcSecret, "12345", now)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate("not base32!", "123456", now)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	uri, err := url.Parse(totp.URI("Auth", "alice", secret))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Auth:alice", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Auth", uri.Query().Get("issuer"))
+}
@@ -0,0 +1,54 @@
+package pkg
+
+import (
+	"Auth/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyGormRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyGormRepository stores API keys over the connection of the user
+// repository.
+func NewAPIKeyGormRepository(users *UserGormRepository) *APIKeyGormRepository {
+	return &APIKeyGormRepository{db: users.db}
+}
+
+func (r *APIKeyGormRepository) Create(key *model.APIKey) error {
+	return r.db.Omit("User").Create(key).Error
+}
+
+func (r *APIKeyGormRepository) FindByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := r.db.WithContext(ctx).Preload("User").Where("hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *APIKeyGormRepository) ListByUser(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *APIKeyGormRepository) TouchLastUsed(id uint, at time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+func (r *APIKeyGormRepository) Revoke(userID, id uint) error {
+	result := r.db.Where("user_id = ? AND id = ?", userID, id).Delete(&model.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package pkg
+
+import (
+	"Auth/internal/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type ClientGormRepository struct {
+	db *gorm.DB
+}
+
+// NewClientGormRepository stores OAuth clients over the connection of the
+// user repository.
+func NewClientGormRepository(users *UserGormRepository) *ClientGormRepository {
+	return &ClientGormRepository{db: users.db}
+}
+
+func (r *ClientGormRepository) Create(client *model.Client) error {
+	return r.db.Create(client).Error
+}
+
+func (r *ClientGormRepository) FindByClientID(ctx context.Context, clientID string) (*model.Client, error) {
+	var client model.Client
+	if err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
@@ -2,13 +2,16 @@ package pkg
 
 import (
 	"Auth/config"
+	"Auth/internal/identity"
 	"Auth/internal/model"
 	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserGormRepository struct {
@@ -27,7 +30,7 @@ func InitializeDatabase(cfg *config.Config) *UserGormRepository {
 		log.Fatalf("could not connect to the database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.User{})
+	err = db.AutoMigrate(&model.User{}, &model.Role{}, &model.Client{}, &model.APIKey{})
 	if err != nil {
 		log.Fatalf("failed migration: %v", err)
 	}
@@ -39,16 +42,100 @@ func (r *UserGormRepository) Create(user *model.User) error {
 	return r.db.Create(user).Error
 }
 
+// whereUsername matches the normalized username case-insensitively, which
+// uses the lower(username) index and finds rows stored before usernames
+// were normalized.
+func whereUsername(db *gorm.DB, username string) *gorm.DB {
+	return db.Where("lower(username) = lower(?)", identity.NormalizeUsername(username))
+}
+
 func (r *UserGormRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
 	var user model.User
-	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+	if err := whereUsername(r.db.WithContext(ctx).Preload("Roles"), username).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserGormRepository) Delete(username string) error {
-	result := r.db.Where("username = ?", username).Unscoped().Delete(&model.User{})
+func (r *UserGormRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.WithContext(ctx).Preload("Roles").First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// List returns a page of the users matching the filter, ordered by ID, and
+// how many match in total.
+func (r *UserGormRepository) List(ctx context.Context, filter model.UserFilter) ([]model.User, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.User{})
+	if filter.Username != "" {
+		query = query.Where("lower(username) LIKE ? ESCAPE '\\'", containsPattern(identity.NormalizeUsername(filter.Username)))
+	}
+	if filter.Email != "" {
+		query = query.Where("lower(email) LIKE ? ESCAPE '\\'", containsPattern(strings.ToLower(strings.TrimSpace(filter.Email))))
+	}
+	if filter.Role != "" {
+		query = query.Where(
+			"EXISTS (SELECT 1 FROM user_roles JOIN roles ON roles.id = user_roles.role_id WHERE user_roles.user_id = users.id AND roles.name = ?)",
+			filter.Role,
+		)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	if err := query.Preload("Roles").Order("id").Offset(filter.Offset).Limit(filter.Limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func containsPattern(value string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+}
+
+// FindRoles returns the roles with the given names, names without a role
+// are left out.
+func (r *UserGormRepository) FindRoles(ctx context.Context, names []string) ([]model.Role, error) {
+	roles := []model.Role{}
+	if len(names) == 0 {
+		return roles, nil
+	}
+	if err := r.db.WithContext(ctx).Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// SetRoles replaces the roles of the user.
+func (r *UserGormRepository) SetRoles(user *model.User, roles []model.Role) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(roles) == 0 {
+			return tx.Model(user).Association("Roles").Clear()
+		}
+		return tx.Model(user).Association("Roles").Replace(roles)
+	})
+	if err != nil {
+		return err
+	}
+	user.Roles = roles
+	return nil
+}
+
+func (r *UserGormRepository) Update(user *model.User) error {
+	return r.db.Omit(clause.Associations).Save(user).Error
+}
+
+func (r *UserGormRepository) IncrementTokenVersion(username string) error {
+	result := whereUsername(r.db.Model(&model.User{}), username).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1"))
 	if result.Error != nil {
 		return result.Error
 	}
@@ -57,3 +144,16 @@ func (r *UserGormRepository) Delete(username string) error {
 	}
 	return nil
 }
+
+func (r *UserGormRepository) Delete(username string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user model.User
+		if err := whereUsername(tx, username).First(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Association("Roles").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&user).Error
+	})
+}
@@ -14,6 +14,26 @@ var (
 	ctx         = context.Background()
 )
 
+// incrementScript sets the expiration together with the first increment, a
+// separate EXPIRE could be lost and leave a counter that never resets.
+var incrementScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// addToSetScript adds the member and extends the expiration of the set to
+// cover it, without cutting short the members added before.
+var addToSetScript = redis.NewScript(`
+redis.call("SADD", KEYS[1], ARGV[1])
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 1
+`)
+
 type RedisRepository struct {
 	client *redis.Client
 }
@@ -37,6 +57,10 @@ func (r *RedisRepository) Set(key string, value any, expiration time.Duration) e
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+func (r *RedisRepository) SetNX(key string, value any, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (r *RedisRepository) Get(key string) (string, error) {
 	return r.client.Get(ctx, key).Result()
 }
@@ -44,3 +68,19 @@ func (r *RedisRepository) Get(key string) (string, error) {
 func (r *RedisRepository) Delete(key string) error {
 	return r.client.Del(ctx, key).Err()
 }
+
+func (r *RedisRepository) Increment(key string, expiration time.Duration) (int64, error) {
+	return incrementScript.Run(ctx, r.client, []string{key}, expiration.Milliseconds()).Int64()
+}
+
+func (r *RedisRepository) AddToSet(key, member string, expiration time.Duration) error {
+	return addToSetScript.Run(ctx, r.client, []string{key}, member, expiration.Milliseconds()).Err()
+}
+
+func (r *RedisRepository) SetMembers(key string) ([]string, error) {
+	return r.client.SMembers(ctx, key).Result()
+}
+
+func (r *RedisRepository) RemoveFromSet(key, member string) error {
+	return r.client.SRem(ctx, key, member).Err()
+}
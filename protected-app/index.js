@@ -6,7 +6,11 @@ app.get('/public', (req, res) => {
 });
 
 app.get('/protected', (req, res) => {
-  res.send('protected');
+  res.send(`protected for ${req.get('X-Auth-User')}`);
+});
+
+app.get('/admin', (req, res) => {
+  res.send('admin');
 });
 
 app.listen(8080, () => {